@@ -0,0 +1,62 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// The destroy command removes the engine objects left by an OpenShift cluster,
+// with the credentials of the cluster credentials secret.
+package main
+
+import (
+	"flag"
+
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/providerIDcontroller"
+)
+
+func main() {
+	klog.InitFlags(nil)
+
+	clusterID := flag.String(
+		"cluster-id",
+		"",
+		"The ID of the OpenShift cluster, the VMs are tagged with, e.g the infrastructure name.",
+	)
+	namespace := flag.String(
+		"namespace",
+		providerIDcontroller.NAMESPACE,
+		"The namespace of the credentials secret.",
+	)
+	credentialsSecret := flag.String(
+		"credentials-secret",
+		providerIDcontroller.CREDENTIALS_SECRET,
+		"The name of the secret holding the engine credentials.",
+	)
+	flag.Parse()
+	if *clusterID == "" {
+		klog.Fatal("--cluster-id must be set")
+	}
+
+	c, err := client.New(config.GetConfigOrDie(), client.Options{})
+	if err != nil {
+		klog.Fatalf("Failed creating the client: %v", err)
+	}
+	creds, err := clients.GetCredentialsSecret(c, *namespace, *credentialsSecret)
+	if err != nil {
+		klog.Fatalf("Failed getting the credentials: %v", err)
+	}
+	connection, err := clients.NewConnection(creds)
+	if err != nil {
+		klog.Fatalf("Failed connecting to the engine: %v", err)
+	}
+	defer connection.Close()
+
+	if err := clients.DestroyCluster(connection, *clusterID); err != nil {
+		klog.Fatalf("Failed destroying cluster %s: %v", *clusterID, err)
+	}
+	klog.Infof("Destroyed cluster %s", *clusterID)
+}
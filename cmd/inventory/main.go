@@ -0,0 +1,92 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// The inventory command lists the machines of an OpenShift cluster side by side
+// with their VMs, for troubleshooting machines and VMs which went out of sync.
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/config"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/providerIDcontroller"
+)
+
+func main() {
+	klog.InitFlags(nil)
+
+	clusterID := flag.String(
+		"cluster-id",
+		"",
+		"The ID of the OpenShift cluster, the machines are labeled and the VMs are tagged with.",
+	)
+	namespace := flag.String(
+		"namespace",
+		providerIDcontroller.NAMESPACE,
+		"The namespace of the credentials secret.",
+	)
+	credentialsSecret := flag.String(
+		"credentials-secret",
+		providerIDcontroller.CREDENTIALS_SECRET,
+		"The name of the secret holding the engine credentials.",
+	)
+	flag.Parse()
+	if *clusterID == "" {
+		klog.Fatal("--cluster-id must be set")
+	}
+
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		klog.Fatalf("Failed registering the API types: %v", err)
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		klog.Fatalf("Failed registering the machine API types: %v", err)
+	}
+	c, err := client.New(config.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		klog.Fatalf("Failed creating the client: %v", err)
+	}
+	creds, err := clients.GetCredentialsSecret(c, *namespace, *credentialsSecret)
+	if err != nil {
+		klog.Fatalf("Failed getting the credentials: %v", err)
+	}
+	connection, err := clients.NewConnection(creds)
+	if err != nil {
+		klog.Fatalf("Failed connecting to the engine: %v", err)
+	}
+	defer connection.Close()
+
+	entries, err := clients.ClusterInventory(context.Background(), c, connection, *clusterID)
+	if err != nil {
+		klog.Fatalf("Failed listing cluster %s: %v", *clusterID, err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "NAMESPACE\tMACHINE\tPHASE\tNODE\tVM\tVM ID\tSTATUS\tHOST\tIPS")
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			orNone(e.MachineNamespace), orNone(e.MachineName), orNone(e.MachinePhase), orNone(e.NodeName),
+			orNone(e.VMName), orNone(e.VMID), orNone(e.VMStatus), orNone(e.Host), orNone(strings.Join(e.IPs, ",")))
+	}
+	w.Flush()
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
@@ -17,18 +17,30 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
+	"hash/fnv"
 	"os"
+	"strconv"
+	"strings"
 	"time"
 
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/apis"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/defaultscontroller"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/machine"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/metrics"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/migrationcontroller"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/prewarmcontroller"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/providerIDcontroller"
+	ovirtwebhook "github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/webhook"
 
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	capimachine "github.com/openshift/machine-api-operator/pkg/controller/machine"
@@ -39,6 +51,8 @@ import (
 	logz "sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/manager"
 	"sigs.k8s.io/controller-runtime/pkg/manager/signals"
+	ctrlmetrics "sigs.k8s.io/controller-runtime/pkg/metrics"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
 )
 
 // The default durations for the leader election operations.
@@ -49,6 +63,15 @@ var (
 	syncPeriod    = 10 * time.Minute
 )
 
+// The machine controller of the machine API, draining the nodes of the deleted machines, and the leader election.
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machines;machines/status,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups="",resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=pods/eviction,verbs=create
+// +kubebuilder:rbac:groups=apps,resources=daemonsets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=configmaps,verbs=get;list;watch;create;update
+// +kubebuilder:rbac:groups=coordination.k8s.io,resources=leases,verbs=get;list;watch;create;update
+
 func main() {
 	klog.InitFlags(nil)
 
@@ -76,33 +99,151 @@ func main() {
 		"The namespace of resource object that is used for locking during leader election. If unspecified and running in cluster, defaults to the service account namespace for the controller. Required for leader-election outside of a cluster.",
 	)
 
+	credentialsNamespace := flag.String(
+		"credentials-namespace",
+		providerIDcontroller.NAMESPACE,
+		"The namespace of the cluster credentials secret, the nodes and the machine provider defaults are checked with.",
+	)
+
+	credentialsSecret := flag.String(
+		"credentials-secret",
+		providerIDcontroller.CREDENTIALS_SECRET,
+		"The name of the cluster credentials secret, the nodes and the machine provider defaults are checked with.",
+	)
+
 	leaderElect := flag.Bool(
 		"leader-elect",
 		false,
 		"Start a leader election client and gain leadership before executing the main loop. Enable this when running replicated components for high availability.",
 	)
 
+	leaderElectionID := flag.String(
+		"leader-election-id",
+		"",
+		"The name of the resource object that is used for locking during leader election. If unspecified, it's derived from --machine-selector, so the deployments reconciling different machines don't share a leader.",
+	)
+
 	leaderElectLeaseDuration := flag.Duration(
 		"leader-elect-lease-duration",
 		leaseDuration,
 		"The duration that non-leader candidates will wait after observing a leadership renewal until attempting to acquire leadership of a led but unrenewed leader slot. This is effectively the maximum duration that a leader can be stopped before it is replaced by another candidate. This is only applicable if leader election is enabled.",
 	)
 
+	connectionPoolSize := flag.Int(
+		"connection-pool-size",
+		clients.DefaultConnectionPoolSize,
+		"The maximum number of oVirt engine connections kept open, one per credentials secret. The least recently used connection is closed when the pool is full.",
+	)
+
+	connectionIdleTimeout := flag.Duration(
+		"connection-idle-timeout",
+		clients.DefaultConnectionIdleTimeout,
+		"The duration an unused oVirt engine connection is kept open.",
+	)
+
+	lifecycleWebhookURL := flag.String(
+		"lifecycle-webhook-url",
+		"",
+		"The URL machine created, running and deleted transitions are posted to. If unspecified, no webhook is called.",
+	)
+
+	lifecycleWebhookSecret := flag.String(
+		"lifecycle-webhook-secret",
+		"",
+		"The namespace/name of the secret authenticating the lifecycle webhook requests, with a token key, or username and password keys.",
+	)
+
+	storageOvercommitThreshold := flag.Int(
+		"storage-overcommit-threshold",
+		100,
+		"The percentage of the free space of a storage domain the OS disks of the machines a MachineSet still creates may take before a warning event is emitted on the MachineSet. If 0, the storage isn't checked.",
+	)
+
+	evacuateBeforeDelete := flag.Bool(
+		"evacuate-before-delete",
+		false,
+		"Wait for the node of a deleted machine to be drained, and release its VM from the enforcing affinity groups before stopping it.",
+	)
+
+	shutdownTimeout := flag.Duration(
+		"shutdown-timeout",
+		0,
+		"How long the guest of a deleted machine may take to shut down gracefully before its VM is powered off, unless the machine provider spec sets another timeout. If 0, the VM is powered off right away.",
+	)
+
+	createTimeout := flag.Duration(
+		"create-timeout",
+		clients.DefaultCloneTimeout,
+		"The time the template disks of a machine may take to be cloned before its creation fails.",
+	)
+
+	startTimeout := flag.Duration(
+		"start-timeout",
+		0,
+		"The time a started VM may take to come up before its machine is moved to the Failed phase, to be replaced by a MachineHealthCheck. If 0, the VM is waited for.",
+	)
+
+	diskExtensionTimeout := flag.Duration(
+		"disk-extension-timeout",
+		clients.DefaultDiskExtensionTimeout,
+		"The time the extension of the OS disk of a machine may take.",
+	)
+
+	deleteTimeout := flag.Duration(
+		"delete-timeout",
+		0,
+		"The time the removal of a VM may take from its first shutdown request before it is forced, as with the force-delete annotation. If 0, the removal isn't forced.",
+	)
+
+	clusterMachineQuota := flag.String(
+		"cluster-machine-quota",
+		"",
+		"Comma separated <oVirt cluster ID>=<maximum machines> pairs. A machine isn't created in an oVirt cluster holding its maximum. Clusters which aren't listed have no quota.",
+	)
+
+	machineSelector := flag.String(
+		"machine-selector",
+		"",
+		"Label selector of the machines this controller reconciles, e.g for a canary of a new provider version. The other machines are left to the provider deployments selecting them. If empty, all the machines are reconciled.",
+	)
+
+	webhookPort := flag.Int(
+		"webhook-port",
+		0,
+		"The port the machine defaulting webhook is served at. If 0, the webhook is disabled.",
+	)
+
+	webhookCertDir := flag.String(
+		"webhook-cert-dir",
+		"/etc/machine-api-operator/tls",
+		"The directory holding the tls.crt and tls.key of the webhook server.",
+	)
+
 	flag.Parse()
+	quota, err := parseClusterMachineQuota(*clusterMachineQuota)
+	if err != nil {
+		klog.Fatalf("Invalid --cluster-machine-quota: %v", err)
+	}
+	selector, err := labels.Parse(*machineSelector)
+	if err != nil {
+		klog.Fatalf("Invalid --machine-selector: %v", err)
+	}
 	log := logz.New().WithName("ovirt-controller-manager")
 
 	entryLog := log.WithName("entrypoint")
 
+	// the config of a provider running outside of the cluster is read from --kubeconfig
 	cfg := config.GetConfigOrDie()
 	if cfg == nil {
 		panic(fmt.Errorf("GetConfigOrDie didn't die and cfg is nil"))
 	}
+	credentials := types.NamespacedName{Namespace: *credentialsNamespace, Name: *credentialsSecret}
 
 	// Setup a Manager
 	opts := manager.Options{
 		LeaderElection:          *leaderElect,
 		LeaderElectionNamespace: *leaderElectResourceNamespace,
-		LeaderElectionID:        "cluster-api-provider-ovirt-leader",
+		LeaderElectionID:        electionID(*leaderElectionID, selector),
 		LeaseDuration:           leaderElectLeaseDuration,
 		HealthProbeBindAddress:  *healthAddr,
 		SyncPeriod:              &syncPeriod,
@@ -111,6 +252,10 @@ func main() {
 		RetryPeriod:   &retryPeriod,
 		RenewDeadline: &renewDeadline,
 	}
+	if *webhookPort != 0 {
+		opts.Port = *webhookPort
+		opts.CertDir = *webhookCertDir
+	}
 	if *watchNamespace != "" {
 		opts.Namespace = *watchNamespace
 		klog.Infof("Watching machine-api objects only in namespace %q for reconciliation.", opts.Namespace)
@@ -147,14 +292,65 @@ func main() {
 		MachinesClient: cs.MachineV1beta1(),
 		KubeClient:     kubeClient,
 		EventRecorder:  mgr.GetEventRecorderFor("ovirtprovider"),
+		Config:         cfg,
+
+		ConnectionPoolSize:    *connectionPoolSize,
+		ConnectionIdleTimeout: *connectionIdleTimeout,
+
+		LifecycleWebhookURL:    *lifecycleWebhookURL,
+		LifecycleWebhookSecret: *lifecycleWebhookSecret,
+
+		StorageOvercommitThreshold: *storageOvercommitThreshold,
+		EvacuateBeforeDelete:       *evacuateBeforeDelete,
+		ShutdownTimeout:            *shutdownTimeout,
+		CreateTimeout:              *createTimeout,
+		StartTimeout:               *startTimeout,
+		DiskExtensionTimeout:       *diskExtensionTimeout,
+		DeleteTimeout:              *deleteTimeout,
+		ClusterMachineQuota:        quota,
 	})
 	if err != nil {
 		panic(err)
 	}
 
-	capimachine.AddWithActuator(mgr, machineActuator)
+	machineMgr := mgr
+	if !selector.Empty() {
+		machineMgr = machine.NewMachineSelectingManager(mgr, selector)
+		klog.Infof("Reconciling only the machines matching %q.", selector.String())
+	}
+	capimachine.AddWithActuator(machineMgr, machineActuator)
+	if err := mgr.Add(machineActuator.Notifier()); err != nil {
+		klog.Fatal(err)
+	}
 
-	providerIDcontroller.Add(mgr, manager.Options{})
+	// report missing engine permits at startup instead of on the first machine
+	err = mgr.Add(manager.RunnableFunc(func(_ context.Context) error {
+		if err := machineActuator.CheckPermissions(credentials.Namespace, credentials.Name); err != nil {
+			klog.Warningf("Failed checking the engine permits: %v", err)
+		}
+		return nil
+	}))
+	if err != nil {
+		klog.Fatal(err)
+	}
+
+	if *webhookPort != 0 {
+		mgr.GetWebhookServer().Register(ovirtwebhook.DefaultingPath,
+			&webhook.Admission{Handler: ovirtwebhook.NewMachineDefaulter(mgr.GetClient())})
+	}
+
+	ctrlmetrics.Registry.MustRegister(metrics.NewProvisioningCollector(mgr.GetClient()))
+
+	providerIDcontroller.Add(mgr, manager.Options{}, credentials, machineActuator.Connections())
+	if err := defaultscontroller.Add(mgr, manager.Options{}, credentials, machineActuator.Connections()); err != nil {
+		klog.Fatal(err)
+	}
+	if err := migrationcontroller.Add(machineMgr, manager.Options{}, machineActuator.Connections()); err != nil {
+		klog.Fatal(err)
+	}
+	if err := prewarmcontroller.Add(mgr, manager.Options{}, machineActuator.Connections()); err != nil {
+		klog.Fatal(err)
+	}
 
 	if err := mgr.AddReadyzCheck("ping", healthz.Ping); err != nil {
 		klog.Fatal(err)
@@ -169,3 +365,37 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// electionID returns the leader election ID, derived from the machine selector unless set
+func electionID(id string, selector labels.Selector) string {
+	if id != "" {
+		return id
+	}
+	if selector.Empty() {
+		return "cluster-api-provider-ovirt-leader"
+	}
+	h := fnv.New32a()
+	h.Write([]byte(selector.String()))
+	return fmt.Sprintf("cluster-api-provider-ovirt-leader-%08x", h.Sum32())
+}
+
+// parseClusterMachineQuota parses the <cluster ID>=<maximum machines> pairs of the quota flag
+func parseClusterMachineQuota(value string) (map[string]int, error) {
+	quota := make(map[string]int)
+	for _, pair := range strings.Split(value, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		parts := strings.SplitN(pair, "=", 2)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("invalid quota %q, expected <cluster ID>=<maximum machines>", pair)
+		}
+		max, err := strconv.Atoi(parts[1])
+		if err != nil || max < 0 {
+			return nil, fmt.Errorf("invalid maximum machines in quota %q", pair)
+		}
+		quota[parts[0]] = max
+	}
+	return quota, nil
+}
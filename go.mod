@@ -9,6 +9,8 @@ require (
 	github.com/openshift/machine-api-operator v0.2.1-0.20210104142355-8e6ae0acdfcf
 	github.com/ovirt/go-ovirt v0.0.0-20210112072624-e4d3b104de71
 	github.com/pkg/errors v0.9.1
+	github.com/prometheus/client_golang v1.7.1
+	golang.org/x/tools v0.0.0-20201020123448-f5c826d1900e
 	k8s.io/api v0.20.0
 	k8s.io/apimachinery v0.20.0
 	k8s.io/client-go v0.20.0
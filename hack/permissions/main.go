@@ -0,0 +1,45 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// permissions prints the manifest of the minimal engine role the provider requires.
+// Run it with "make permissions-manifest" after changing clients.RequiredPermits.
+package main
+
+import (
+	"fmt"
+
+	"sigs.k8s.io/yaml"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+)
+
+type role struct {
+	Name           string   `json:"name"`
+	Description    string   `json:"description"`
+	Administrative bool     `json:"administrative"`
+	Permits        []string `json:"permits"`
+	Scope          []string `json:"scope"`
+}
+
+func main() {
+	manifest, err := yaml.Marshal(role{
+		Name:           "OpenShiftMachineAPI",
+		Description:    "Minimal role of the engine user of cluster-api-provider-ovirt",
+		Administrative: true,
+		Permits:        clients.RequiredPermits,
+		Scope: []string{
+			"the oVirt cluster of the machines",
+			"the storage domains of the template disks and the OS disks",
+			"the vNIC profiles of the machines",
+			"the templates of the machines",
+			"system, for tag_management only",
+		},
+	})
+	if err != nil {
+		panic(err)
+	}
+	fmt.Print("# Code generated by hack/permissions. DO NOT EDIT.\n")
+	fmt.Print(string(manifest))
+}
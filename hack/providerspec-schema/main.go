@@ -0,0 +1,64 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// providerspec-schema prints the OpenAPI v3 schema of OvirtMachineProviderSpec, generated
+// from its Go type the way controller-gen generates the CRD schemas. The providerSpec of
+// the machines and MachineSets can be validated against it before they are applied.
+// Run it with "make providerspec-schema" after changing the provider spec.
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"golang.org/x/tools/go/packages"
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	crdmarkers "sigs.k8s.io/controller-tools/pkg/crd/markers"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+	"sigs.k8s.io/controller-tools/pkg/markers"
+)
+
+const (
+	apisPackage  = "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	providerSpec = "OvirtMachineProviderSpec"
+)
+
+func main() {
+	pkgs, err := loader.LoadRoots(apisPackage)
+	if err != nil {
+		panic(err)
+	}
+	registry := &markers.Registry{}
+	if err := crdmarkers.Register(registry); err != nil {
+		panic(err)
+	}
+	parser := &crd.Parser{
+		Collector: &markers.Collector{Registry: registry},
+		Checker:   &loader.TypeChecker{},
+	}
+	crd.AddKnownTypes(parser)
+	for _, pkg := range pkgs {
+		parser.NeedPackage(pkg)
+	}
+
+	typ := crd.TypeIdent{Package: pkgs[0], Name: providerSpec}
+	parser.NeedFlattenedSchemaFor(typ)
+	// like controller-gen, the type errors of the partially checked imports are ignored
+	if loader.PrintErrors(pkgs, packages.TypeError) {
+		os.Exit(1)
+	}
+	schema := parser.FlattenedSchemata[typ]
+	// the apiVersion and kind are kept, the metadata of the embedded spec is ignored
+	delete(schema.Properties, "metadata")
+	// the fields the machines leave out may be set by the defaulting webhook
+	schema.Required = nil
+
+	out, err := json.MarshalIndent(schema, "", "  ")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(string(out))
+}
@@ -2,5 +2,5 @@
 // +k8s:conversion-gen=github.com/rgolangh/cluster-api/cluster-api-provider-ovirt/pkg/apis/ovirtprovider
 // +k8s:openapi-gen=true
 // +k8s:defaulter-gen=TypeMeta
-// +groupName=ovirtprovider.machine.openshift.io
+// +groupName=ovirtproviderconfig.machine.openshift.io
 package v1beta1
@@ -18,7 +18,7 @@ limitations under the License.
 // +k8s:deepcopy-gen=package,register
 // +k8s:conversion-gen=github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider
 // +k8s:defaulter-gen=TypeMeta
-// +groupName=ovirtprovider.machine.openshift.io
+// +groupName=ovirtproviderconfig.machine.openshift.io
 package v1beta1
 
 import (
@@ -107,6 +107,27 @@ func ProviderSpecFromRawExtension(rawExtension *runtime.RawExtension) (*OvirtMac
 	return spec, nil
 }
 
+// UnmarshalJSON decodes the spec, also accepting the AffinityGroupsNames key
+// written by releases which had a malformed json tag on that field.
+func (s *OvirtMachineProviderSpec) UnmarshalJSON(data []byte) error {
+	type providerSpec OvirtMachineProviderSpec
+	if err := json.Unmarshal(data, (*providerSpec)(s)); err != nil {
+		return err
+	}
+	if s.AffinityGroupsNames != nil {
+		return nil
+	}
+
+	legacy := struct {
+		AffinityGroupsNames []string `json:"AffinityGroupsNames,omitempty"`
+	}{}
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return err
+	}
+	s.AffinityGroupsNames = legacy.AffinityGroupsNames
+	return nil
+}
+
 // ProviderStatusFromRawExtension unmarshals a raw extension into a OvirtMachineProviderStatus type
 func ProviderStatusFromRawExtension(rawExtension *runtime.RawExtension) (*OvirtMachineProviderStatus, error) {
 	if rawExtension == nil {
@@ -34,6 +34,13 @@ type OvirtMachineProviderSpec struct {
 	// UserData to apply to the instance
 	UserDataSecret *corev1.LocalObjectReference `json:"userDataSecret,omitempty"`
 
+	// InitializationType is how the guest applies the user data, "ignition", "cloud-init"
+	// or "sysprep". With cloud-init, the user data is cloud-init content, e.g a cloud-config,
+	// for worker images other than RHCOS. With sysprep, the user data is the unattend answer
+	// file of a Windows template, e.g for Windows MachineSets. If empty, the user data is an
+	// ignition config.
+	InitializationType string `json:"initialization_type,omitempty"`
+
 	// CredentialsSecret is a reference to the secret with oVirt credentials.
 	CredentialsSecret *corev1.LocalObjectReference `json:"credentialsSecret,omitempty"`
 
@@ -46,23 +53,80 @@ type OvirtMachineProviderSpec struct {
 	// The VM template this instance will be created from.
 	TemplateName string `json:"template_name"`
 
+	// TemplateID is the ID of the VM template, it takes precedence over TemplateName
+	// since the template names are only unique within a data center.
+	TemplateID string `json:"template_id,omitempty"`
+
+	// TemplateTag selects the template by an oVirt tag when TemplateName is empty.
+	// The newest template carrying the tag is resolved when the VM is created, so the
+	// templates can be rotated by moving the tag, without editing every MachineSet.
+	TemplateTag string `json:"template_tag,omitempty"`
+
+	// TemplateVersion is the version number of the template sub version the VM is
+	// created from, e.g "3", or "latest" for the newest sub version, resolved when
+	// the VM is created. If empty, the base version of the template is used.
+	TemplateVersion string `json:"template_version,omitempty"`
+
 	// the oVirt cluster this VM instance belongs too.
 	ClusterId string `json:"cluster_id"`
 
+	// Description is the description of the VM. "{cluster_id}", "{machineset}" and
+	// "{machine}" are replaced by the OpenShift cluster ID, the MachineSet name and
+	// the machine name, making the owner of the VM obvious in the oVirt admin portal.
+	Description string `json:"description,omitempty"`
+
+	// Comment is the comment of the VM, with the same substitutions as the description.
+	// The provider appends the UID of the owning machine to it.
+	Comment string `json:"comment,omitempty"`
+
+	// DataCenterId is the data center the cluster is expected to belong to. If set, the
+	// machine creation fails when the cluster belongs to another data center.
+	DataCenterId string `json:"data_center_id,omitempty"`
+
 	// InstanceTypeId defines the VM instance type and overrides
 	// the hardware parameters of the created VM, including cpu and memory.
 	// If InstanceTypeId is passed, all memory and cpu variables will be ignored.
 	InstanceTypeId string `json:"instance_type_id,omitempty"`
 
+	// InstanceTypeName selects the VM instance type by name instead of ID, so the
+	// MachineSets are portable across engines. It is resolved when the VM is created.
+	InstanceTypeName string `json:"instance_type_name,omitempty"`
+
 	// CPU defines the VM CPU.
 	CPU *CPU `json:"cpu,omitempty"`
 
 	// MemoryMB is the size of a VM's memory in MiBs.
 	MemoryMB int32 `json:"memory_mb,omitempty"`
 
+	// GuaranteedMemoryMB is the size in MiBs of the VM memory the engine guarantees
+	// to be available on the host. If 0, it is inherited from the template.
+	GuaranteedMemoryMB int32 `json:"guaranteed_memory_mb,omitempty"`
+
+	// MaxMemoryMB is the size in MiBs the VM memory can be hot plugged up to.
+	// If 0, the engine sets it to 4 times the VM memory.
+	MaxMemoryMB int32 `json:"max_memory_mb,omitempty"`
+
+	// Ballooning enables the memory balloon device of the VM. Disabling it keeps the
+	// engine from reclaiming the VM memory, for latency sensitive workloads.
+	// If unset, it is inherited from the template.
+	Ballooning *bool `json:"ballooning,omitempty"`
+
 	// OSDisk is the the root disk of the node.
 	OSDisk *Disk `json:"os_disk,omitempty"`
 
+	// TemplateDisks place the disks of a template with several disks, by their alias,
+	// on their own storage domain and disk profile when the template is cloned. The
+	// storage domain of the OS disk, if set, takes precedence for the bootable disk.
+	// The disks which aren't listed are cloned to the storage domain of the template disk.
+	TemplateDisks []TemplateDisk `json:"template_disks,omitempty"`
+
+	// Clone makes the VM disks full copies of the template disks, independent of the
+	// template. If false, the disks are thin overlays depending on the template disks,
+	// which are created faster but stay on the template storage domains, so the OS disk
+	// and the template disks can't be placed on other storage domains. If unset, the
+	// disks are cloned only when they are placed on other storage domains.
+	Clone *bool `json:"clone,omitempty"`
+
 	// VMType defines the workload type the instance will
 	// be used for and this effects the instance parameters.
 	// One of "desktop, server, high_performance"
@@ -73,9 +137,389 @@ type OvirtMachineProviderSpec struct {
 	// be created, unless the list is empty or nil
 	NetworkInterfaces []*NetworkInterface `json:"network_interfaces,omitempty"`
 
+	// NetworkConfiguration sets static IP addresses on the guest NICs through the VM
+	// initialization, for networks without DHCP. As the addresses are static, it fits
+	// machines created one by one, rather than MachineSets of several machines.
+	NetworkConfiguration *NetworkConfiguration `json:"network_configuration,omitempty"`
+
+	// AddressFamily is the preferred IP family of the machine addresses, "ipv4" or "ipv6".
+	// The addresses of the family are listed first in the machine status, so the kubelet
+	// of a dual-homed machine selects its node IP from that family. The addresses are
+	// otherwise ordered by the NIC name, keeping the selection stable across reboots.
+	AddressFamily string `json:"address_family,omitempty"`
+
+	// NoGuestAgent declares a compute-only appliance machine whose guest runs without
+	// a guest agent. The machine addresses are then the static addresses of the
+	// NetworkConfiguration, which must be set, instead of the addresses the agent
+	// reports, and the VM is powered off instead of shut down gracefully.
+	NoGuestAgent bool `json:"no_guest_agent,omitempty"`
+
 	// VMAffinityGroup contains the name of the OpenShift cluster affinity groups
 	// It will be used to add the newly created machine to the affinity groups
-	AffinityGroupsNames []string `json:"affinity_groups_names,omitempty`
+	AffinityGroupsNames []string `json:"affinity_groups_names,omitempty"`
+
+	// CreateMissingAffinityGroups creates the AffinityGroupsNames which don't exist in the
+	// oVirt cluster yet, as soft VM anti-affinity groups, instead of failing the creation
+	// of the machine. The created groups are marked with the OpenShift cluster ID in
+	// their description, and the teardown of the cluster removes them. The groups
+	// created otherwise, e.g by the installer, aren't removed with the cluster.
+	CreateMissingAffinityGroups bool `json:"create_missing_affinity_groups,omitempty"`
+
+	// AffinityLabels are the names of the affinity labels assigned to the VM, pinning it
+	// to the hosts and VMs of the same labels. The labels must exist in the engine, unless
+	// CreateMissingAffinityLabels is set.
+	AffinityLabels []string `json:"affinity_labels,omitempty"`
+
+	// CreateMissingAffinityLabels creates the AffinityLabels which don't exist in the engine
+	// yet, instead of failing the creation of the machine.
+	CreateMissingAffinityLabels bool `json:"create_missing_affinity_labels,omitempty"`
+
+	// PreferredHosts selects the hosts the VM prefers to run on.
+	// The matching hosts are resolved at create time and set on the VM placement
+	// policy, the engine prefers them when it starts the VM. They don't pin the VM
+	// to them and don't prevent its migration.
+	PreferredHosts *HostSelector `json:"preferred_hosts,omitempty"`
+
+	// PlacementHosts is a list of names of the hosts of the cluster the VM is placed on,
+	// in addition to the preferred hosts. Unlike the preferred hosts, all of them must exist.
+	PlacementHosts []string `json:"placement_hosts,omitempty"`
+
+	// MigrationMode is the migration behavior of the VM, one of "migratable",
+	// "user_migratable" or "pinned". With pinned the VM runs only on its placement
+	// and preferred hosts. If empty, the VM is migratable, unless it must be pinned
+	// for its CPU or NUMA pinning.
+	MigrationMode string `json:"migration_mode,omitempty"`
+
+	// Lease enables a VM lease, held by sanlock on a storage domain, which
+	// protects the VM from running on two hosts when its host becomes unresponsive.
+	// It is meant for control-plane machines, the VM is also made highly available
+	// so the engine restarts it elsewhere once the lease expires.
+	Lease *VMLease `json:"lease,omitempty"`
+
+	// HighlyAvailable makes the engine restart the VM on another host when its host
+	// fails, without waiting for a MachineHealthCheck to replace the machine.
+	// Without a Lease the engine must fence the failed host before restarting the VM,
+	// set a Lease for the HA to work on hosts without power management.
+	// A VM with a Lease is always highly available.
+	HighlyAvailable bool `json:"highly_available,omitempty"`
+
+	// HAPriority orders the restart of the highly available VMs, the VMs with a
+	// higher priority are restarted first. The engine uses 1 for low, 50 for medium
+	// and 100 for high. If 0, it is inherited from the template.
+	HAPriority int32 `json:"ha_priority,omitempty"`
+
+	// ShutdownTimeout is how long the guest of a deleted machine may take to shut
+	// down gracefully before the VM is powered off, e.g "5m" for a stateful worker.
+	// If 0 the VM is powered off right away. If unset, the timeout of the controller is used.
+	ShutdownTimeout *metav1.Duration `json:"shutdown_timeout,omitempty"`
+
+	// ResumeBehavior is what the engine does with the VM when it is paused on a
+	// storage error, one of "auto_resume", "leave_paused" or "kill". A VM with a
+	// Lease must be killed. If empty, it is inherited from the template.
+	ResumeBehavior string `json:"resume_behavior,omitempty"`
+
+	// Console configures the emergency access to the VM consoles.
+	Console *ConsoleAccess `json:"console,omitempty"`
+
+	// GPU attaches vGPU mediated devices to the VM.
+	GPU *GPU `json:"gpu,omitempty"`
+
+	// RNG attaches a random number generator device to the VM, feeding the guest
+	// entropy so workers don't stall on boot. If nil, it is inherited from the template.
+	RNG *RNGDevice `json:"rng,omitempty"`
+
+	// SerialNumber sets the serial number the guest sees, so the licensing tools
+	// in the guest see a stable serial across migrations. If nil, it is inherited
+	// from the template.
+	SerialNumber *SerialNumber `json:"serial_number,omitempty"`
+
+	// Hugepages is the size in KiB of the hugepages backing the VM memory,
+	// 2048 or 1048576. If 0, the VM memory isn't backed by hugepages.
+	Hugepages int32 `json:"hugepages,omitempty"`
+
+	// CustomProperties are VM custom properties, e.g {"viodiskcache": "writeback"}, for the
+	// vdsm hooks installed on the hosts. The engine accepts only the properties its
+	// UserDefinedVMProperties configuration defines. The properties the provider sets for
+	// other fields, mdev_type and hugepages, can't be set here.
+	CustomProperties map[string]string `json:"custom_properties,omitempty"`
+
+	// AutoPinningPolicy is the policy pinning the VM CPUs to the host CPUs,
+	// one of "none", "resize_and_pin". With resize_and_pin the VM CPU topology
+	// is resized to the host one and each vCPU is pinned to a host CPU.
+	// It requires PreferredHosts, the VM is pinned to the selected hosts.
+	AutoPinningPolicy string `json:"auto_pinning_policy,omitempty"`
+
+	// CPUPinning pins the VM vCPUs to host CPUs. It can't be used with the
+	// resize_and_pin auto pinning policy, and it requires PreferredHosts, the VM
+	// is pinned to the selected hosts.
+	CPUPinning []VCPUPin `json:"cpu_pinning,omitempty"`
+
+	// NUMANodes defines the virtual NUMA nodes of the VM. Pinning them to host
+	// NUMA nodes requires PreferredHosts, the VM is pinned to the selected hosts.
+	NUMANodes []NUMANode `json:"numa_nodes,omitempty"`
+
+	// IOThreads is the number of IO threads of the VM, which the VirtIO and VirtIO-SCSI
+	// disks are spread on, improving the disk throughput of storage heavy workers.
+	// If 0, it is inherited from the template.
+	IOThreads int32 `json:"io_threads,omitempty"`
+
+	// MultiQueuesEnabled enables the multiple queues of the VM devices, the engine sets
+	// their number from the VM vCPUs. If unset, it is inherited from the template.
+	MultiQueuesEnabled *bool `json:"multi_queues_enabled,omitempty"`
+
+	// BootDevices is the boot sequence of the VM, a list of "hd", "network" and "cdrom".
+	// e.g ["network", "hd"] boots the VM from PXE first. If empty, it is inherited from the template.
+	BootDevices []string `json:"boot_devices,omitempty"`
+
+	// BiosType is the chipset and firmware of the VM, one of "cluster_default",
+	// "i440fx_sea_bios", "q35_sea_bios", "q35_ovmf" or "q35_secure_boot".
+	// q35_ovmf boots the VM with UEFI, q35_secure_boot with UEFI and SecureBoot.
+	// If empty, it is inherited from the template.
+	BiosType string `json:"bios_type,omitempty"`
+
+	// EmulatedMachine is the machine type QEMU emulates for the VM, e.g "pc-q35-rhel8.4.0"
+	// or "pc-i440fx-rhel7.6.0", for workloads depending on a device model. It must fit
+	// the architecture of the oVirt cluster and the chipset of the BIOS type.
+	// If empty, the emulated machine of the cluster is used.
+	EmulatedMachine string `json:"emulated_machine,omitempty"`
+
+	// CustomCompatibilityVersion is the compatibility version the VM runs with instead
+	// of the version of its oVirt cluster, e.g "4.6", so a machine can use the emulated
+	// machines of another version. If empty, the cluster version is used.
+	CustomCompatibilityVersion string `json:"custom_compatibility_version,omitempty"`
+
+	// CPUType overrides the CPU the VM runs with, instead of the oVirt cluster CPU type.
+	// One of "host_passthrough", "host_model", or a libvirt CPU model name, e.g "Skylake-Server".
+	CPUType string `json:"cpu_type,omitempty"`
+
+	// CPUShares is the relative weight of the VM CPUs against the other VMs of its host,
+	// for noisy neighbor control. The engine uses 512 for low, 1024 for medium and 2048
+	// for high. If 0, the CPU shares are disabled.
+	CPUShares int32 `json:"cpu_shares,omitempty"`
+
+	// Stateless creates a stateless VM, whose disk changes are dropped when it is shut
+	// down, e.g for the ephemeral machines of test MachineSets. A stateless VM can't be
+	// highly available.
+	Stateless bool `json:"stateless,omitempty"`
+
+	// DeleteProtected marks the VM delete protected in the engine, so it can't be removed
+	// by mistake, e.g from the administration portal. The protection is cleared by the
+	// provider only when the machine itself is deleted.
+	DeleteProtected bool `json:"delete_protected,omitempty"`
+}
+
+// TemplateVersionLatest selects the newest sub version of the template
+const TemplateVersionLatest = "latest"
+
+const (
+	// InitializationTypeIgnition applies the user data as an ignition config
+	InitializationTypeIgnition = "ignition"
+	// InitializationTypeCloudInit applies the user data with cloud-init
+	InitializationTypeCloudInit = "cloud-init"
+	// InitializationTypeSysprep applies the user data as a sysprep answer file
+	InitializationTypeSysprep = "sysprep"
+)
+
+const (
+	// AddressFamilyIPv4 lists the IPv4 machine addresses first
+	AddressFamilyIPv4 = "ipv4"
+	// AddressFamilyIPv6 lists the IPv6 machine addresses first
+	AddressFamilyIPv6 = "ipv6"
+)
+
+const (
+	// BootDeviceHD boots the VM from its bootable disk
+	BootDeviceHD = "hd"
+	// BootDeviceNetwork boots the VM from the network with PXE
+	BootDeviceNetwork = "network"
+	// BootDeviceCDROM boots the VM from its CD-ROM
+	BootDeviceCDROM = "cdrom"
+)
+
+const (
+	// MigrationModeMigratable lets the engine migrate the VM
+	MigrationModeMigratable = "migratable"
+	// MigrationModeUserMigratable lets only a user migrate the VM
+	MigrationModeUserMigratable = "user_migratable"
+	// MigrationModePinned keeps the VM on its hosts
+	MigrationModePinned = "pinned"
+)
+
+const (
+	// ResumeBehaviorAutoResume resumes the VM once the storage is available again
+	ResumeBehaviorAutoResume = "auto_resume"
+	// ResumeBehaviorLeavePaused keeps the VM paused
+	ResumeBehaviorLeavePaused = "leave_paused"
+	// ResumeBehaviorKill kills the VM, a highly available VM is then restarted
+	ResumeBehaviorKill = "kill"
+)
+
+const (
+	// CPUTypeHostPassthrough passes the host CPU through to the VM
+	CPUTypeHostPassthrough = "host_passthrough"
+	// CPUTypeHostModel runs the VM with the libvirt CPU model closest to the host CPU
+	CPUTypeHostModel = "host_model"
+)
+
+const (
+	// AutoPinningPolicyNone doesn't pin the VM CPUs
+	AutoPinningPolicyNone = "none"
+	// AutoPinningPolicyResizeAndPin resizes the VM CPU topology to the host one and pins the VM CPUs
+	AutoPinningPolicyResizeAndPin = "resize_and_pin"
+)
+
+const (
+	// Hugepages2M is the size in KiB of 2MiB hugepages
+	Hugepages2M int32 = 2048
+	// Hugepages1G is the size in KiB of 1GiB hugepages
+	Hugepages1G int32 = 1048576
+)
+
+// GPU defines the vGPU mediated devices of the VM
+type GPU struct {
+	// MdevType is the mediated device type of the host GPU, e.g nvidia-22.
+	MdevType string `json:"mdev_type"`
+
+	// Count is the number of devices of the mdev type attached to the VM, 1 by default.
+	Count int `json:"count,omitempty"`
+
+	// NoDisplay disables the display of the devices, for compute only workloads.
+	NoDisplay bool `json:"no_display,omitempty"`
+}
+
+// TemplateDisk defines the placement of a template disk cloned for the VM
+type TemplateDisk struct {
+	// Alias is the alias of the template disk.
+	Alias string `json:"alias"`
+
+	// StorageDomainId is the ID of the storage domain the disk is cloned to.
+	StorageDomainId string `json:"storage_domain_id,omitempty"`
+
+	// StorageDomainName is the name of the storage domain the disk is cloned to.
+	// It is used when StorageDomainId is empty.
+	StorageDomainName string `json:"storage_domain_name,omitempty"`
+
+	// DiskProfileName is the name of the disk profile of the cloned disk, among the disk
+	// profiles of its storage domain. If empty, the default profile of the domain is used.
+	DiskProfileName string `json:"disk_profile_name,omitempty"`
+
+	// WipeAfterDelete requests the storage to securely wipe the disk
+	// when the VM is removed.
+	WipeAfterDelete bool `json:"wipe_after_delete,omitempty"`
+}
+
+// NetworkConfiguration defines the static network configuration of the guest
+type NetworkConfiguration struct {
+	// Nics are the static configurations of the guest NICs.
+	Nics []NicConfiguration `json:"nics"`
+
+	// DNSServers are the addresses of the name servers of the guest.
+	DNSServers []string `json:"dns_servers,omitempty"`
+
+	// DNSSearch are the domains the guest searches host names in.
+	DNSSearch []string `json:"dns_search,omitempty"`
+}
+
+// NicConfiguration defines the static IP address of a guest NIC
+type NicConfiguration struct {
+	// Name is the name of the NIC in the guest, e.g "eth0".
+	Name string `json:"name"`
+
+	// IP is the IPv4 or IPv6 address of the NIC.
+	IP string `json:"ip"`
+
+	// Netmask is the netmask of the address, e.g "255.255.255.0" for an IPv4
+	// address, or the prefix length, e.g "64" for an IPv6 address.
+	Netmask string `json:"netmask"`
+
+	// Gateway is the default gateway reached through the NIC.
+	Gateway string `json:"gateway,omitempty"`
+}
+
+// SerialNumber defines the serial number policy of the VM
+type SerialNumber struct {
+	// Policy is one of "host" for the serial of the host the VM runs on, "vm" for
+	// the ID of the VM, "custom" for the Value, or "none" for the cluster default.
+	Policy string `json:"policy"`
+
+	// Value is the custom serial number. "{cluster_id}", "{machineset}" and
+	// "{machine}" are replaced as in the description of the VM.
+	Value string `json:"value,omitempty"`
+}
+
+// RNGDevice defines the random number generator device of the VM
+type RNGDevice struct {
+	// Source is the host entropy source, "urandom" or "hwrng". The source must be
+	// one of the random number generator sources required by the cluster.
+	Source string `json:"source"`
+
+	// RateBytes limits the entropy read by the VM to this number of bytes per rate
+	// period. If 0, the rate isn't limited.
+	RateBytes int32 `json:"rate_bytes,omitempty"`
+
+	// RatePeriodMs is the period of the rate limit in milliseconds.
+	RatePeriodMs int32 `json:"rate_period_ms,omitempty"`
+}
+
+// VCPUPin pins a vCPU of the VM to host CPUs
+type VCPUPin struct {
+	// VCPU is the index of the vCPU, starting at 0.
+	VCPU int32 `json:"vcpu"`
+
+	// CPUSet is the host CPUs the vCPU runs on, in the libvirt cpuset form, e.g "0-3,^2".
+	CPUSet string `json:"cpu_set"`
+}
+
+// NUMANode defines a virtual NUMA node of the VM
+type NUMANode struct {
+	// Index is the index of the node, starting at 0.
+	Index int32 `json:"index"`
+
+	// Cores is the list of the indexes of the VM vCPUs on the node.
+	Cores []int32 `json:"cores"`
+
+	// MemoryMB is the size of the node memory in MiBs.
+	MemoryMB int32 `json:"memory_mb"`
+
+	// HostNodes is the list of the indexes of the host NUMA nodes the node is pinned to.
+	HostNodes []int32 `json:"host_nodes,omitempty"`
+
+	// TuneMode is the allocation mode of the node memory on the host NUMA nodes,
+	// one of "strict", "interleave", "preferred". Defaults to the engine one, strict.
+	TuneMode string `json:"tune_mode,omitempty"`
+}
+
+// ConsoleAccess defines the break-glass access to the consoles of the VM
+type ConsoleAccess struct {
+	// SerialConsole enables or disables the VirtIO serial console of the VM.
+	// If unset, it is inherited from the template.
+	SerialConsole *bool `json:"serial_console,omitempty"`
+
+	// Graphics is the list of the graphics protocols of the VM consoles, "vnc" and
+	// "spice", or ["headless"] to remove the graphics consoles of the VM.
+	// If empty, the graphics consoles of the template are kept.
+	Graphics []string `json:"graphics,omitempty"`
+
+	// Users is a list of oVirt user names, in the user@domain form, granted
+	// the UserRole on the VM so they can open its consoles, without managing it.
+	// A console password is not configured, the engine issues a one time ticket
+	// per console session.
+	Users []string `json:"users,omitempty"`
+}
+
+const (
+	// GraphicsVNC is the VNC graphics protocol
+	GraphicsVNC = "vnc"
+	// GraphicsSPICE is the SPICE graphics protocol
+	GraphicsSPICE = "spice"
+	// GraphicsHeadless runs the VM without graphics consoles
+	GraphicsHeadless = "headless"
+)
+
+// VMLease defines the storage domain holding the lease of the VM
+type VMLease struct {
+	// StorageDomainId is the ID of the data storage domain the lease is created on.
+	StorageDomainId string `json:"storage_domain_id"`
 }
 
 // CPU defines the VM cpu, made of (Sockets * Cores * Threads)
@@ -96,14 +540,68 @@ type CPU struct {
 type Disk struct {
 	// SizeGB size of the bootable disk in GiB.
 	SizeGB int64 `json:"size_gb"`
+
+	// StorageDomainId is the ID of the storage domain the disk is cloned to.
+	// If empty, the disk is placed on the storage domain of the template disk.
+	StorageDomainId string `json:"storage_domain_id,omitempty"`
+
+	// StorageDomainName is the name of the storage domain the disk is cloned to.
+	// It is used when StorageDomainId is empty.
+	StorageDomainName string `json:"storage_domain_name,omitempty"`
+
+	// Encrypted requires the storage domain to encrypt the disk volume.
+	// Only Managed Block Storage domains with an encryption driver option,
+	// e.g a Ceph domain with "encrypted: true", support it.
+	Encrypted bool `json:"encrypted,omitempty"`
+
+	// Interface is the interface the disk is attached with, one of virtio,
+	// virtio_scsi or ide. If empty, the interface of the template disk is kept.
+	Interface string `json:"interface,omitempty"`
+
+	// WipeAfterDelete requests the storage to securely wipe the disk
+	// when the VM is removed.
+	WipeAfterDelete bool `json:"wipe_after_delete,omitempty"`
+
+	// PassDiscard passes the discard requests of the guest to the storage, so
+	// thin provisioned storage reclaims the space trimmed by the guest.
+	// It requires the virtio_scsi or ide interface.
+	PassDiscard bool `json:"pass_discard,omitempty"`
+}
+
+// HostSelector selects oVirt hosts by their tags
+type HostSelector struct {
+	// Tags is a list of oVirt tag names.
+	// A host is selected if it carries any of the tags.
+	Tags []string `json:"tags,omitempty"`
 }
 
 // NetworkInterface defines a VM network interface
 type NetworkInterface struct {
+	// Name is the name of the network interface in oVirt.
+	// If empty, the interfaces are named nic1..nicN by their position.
+	Name string `json:"name,omitempty"`
+
 	// VNICProfileID the id of the vNic profile
-	VNICProfileID string `json:"vnic_profile_id"`
+	VNICProfileID string `json:"vnic_profile_id,omitempty"`
+
+	// VNICProfileName selects the vNic profile by name instead of by ID, among the
+	// profiles of the networks of the oVirt cluster. A profile name used by several
+	// networks is qualified by its network, e.g "ovirtmgmt/ovirtmgmt".
+	VNICProfileName string `json:"vnic_profile_name,omitempty"`
+
+	// NetworkFilter is the network filter the interface requires, e.g "vdsm-no-mac-spoofing",
+	// or "none" for no filter, as needed for nested virtualization. oVirt sets the filter
+	// on the vNic profile, the vNic profile of the interface must have it.
+	NetworkFilter string `json:"network_filter,omitempty"`
+
+	// NetworkFilterParameters are the parameters of the network filter of the interface,
+	// e.g {"IP": "10.0.0.10"} for clean-traffic. They are set when the interface is created.
+	NetworkFilterParameters map[string]string `json:"network_filter_parameters,omitempty"`
 }
 
+// NetworkFilterNone is the network filter of a vNic profile without a filter
+const NetworkFilterNone = "none"
+
 // +genclient
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 
@@ -149,8 +647,54 @@ type OvirtMachineProviderStatus struct {
 	// Conditions is a set of conditions associated with the Machine to indicate
 	// errors or other status
 	Conditions []OvirtMachineProviderCondition `json:"conditions,omitempty"`
+
+	// CloneStartTime is the time the VM creation from the template started.
+	// It is kept to resume waiting for the template disks clone after a
+	// controller restart without restarting the timeout window.
+	// +optional
+	CloneStartTime *metav1.Time `json:"cloneStartTime,omitempty"`
+
+	// StartTime is the time the VM of the machine was started, until it comes up.
+	// +optional
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// DeleteStartTime is the time the removal of the VM of the deleted machine started,
+	// when the VM was first asked to shut down. The graceful shutdown window and the
+	// delete timeout are counted from it, so they aren't restarted by a controller restart.
+	// +optional
+	DeleteStartTime *metav1.Time `json:"deleteStartTime,omitempty"`
+
+	// CPUs is the number of CPUs of the instance type of the machine, resolved
+	// from the engine when the machine was created.
+	// +optional
+	CPUs int32 `json:"cpus,omitempty"`
+
+	// MemoryMB is the memory in MiBs of the instance type of the machine, resolved
+	// from the engine when the machine was created.
+	// +optional
+	MemoryMB int64 `json:"memoryMB,omitempty"`
+
+	// CreatePhase is the last completed phase of the machine creation.
+	// A creation interrupted by a controller restart is resumed after this phase.
+	// +optional
+	CreatePhase CreatePhase `json:"createPhase,omitempty"`
 }
 
+// CreatePhase is a step of the machine creation
+type CreatePhase string
+
+// The phases of the machine creation, in the order they are performed
+const (
+	CreatePhaseVMCreated             CreatePhase = "VMCreated"
+	CreatePhaseDisksReady            CreatePhase = "DisksReady"
+	CreatePhaseNICsConfigured        CreatePhase = "NICsConfigured"
+	CreatePhaseTagged                CreatePhase = "Tagged"
+	CreatePhaseAffinityGroupsApplied CreatePhase = "AffinityGroupsApplied"
+	CreatePhaseConsoleConfigured     CreatePhase = "ConsoleConfigured"
+	CreatePhaseNUMAConfigured        CreatePhase = "NUMAConfigured"
+	CreatePhaseVMStarted             CreatePhase = "VMStarted"
+)
+
 // OvirtMachineProviderConditionType is a valid value for OvirtMachineProviderCondition.Type
 type OvirtMachineProviderConditionType string
 
@@ -159,6 +703,36 @@ const (
 	// MachineCreated indicates whether the machine has been created or not. If not,
 	// it should include a reason and message for the failure.
 	MachineCreated OvirtMachineProviderConditionType = "MachineCreated"
+
+	// SchedulingFailed indicates the engine couldn't find a host satisfying the
+	// scheduling constraints of the VM. The message carries the filters explanation.
+	SchedulingFailed OvirtMachineProviderConditionType = "SchedulingFailed"
+
+	// TemplateCloned indicates whether the disks of the VM template were cloned.
+	// While the clone is in progress, the message carries the completed percentage.
+	TemplateCloned OvirtMachineProviderConditionType = "TemplateCloned"
+
+	// AddressesReported indicates whether the VM addresses were set on the machine.
+	// If not, the reason explains what they are waiting for, e.g the VM to start.
+	AddressesReported OvirtMachineProviderConditionType = "AddressesReported"
+
+	// PermitsGranted indicates whether the engine user of the credentials secret has
+	// all the permits the provider requires. If not, the message lists the missing ones.
+	PermitsGranted OvirtMachineProviderConditionType = "PermitsGranted"
+
+	// ClusterQuotaExceeded indicates the oVirt cluster of the machine already holds the
+	// maximum number of machines configured for it. The creation is retried until
+	// machines of the cluster are removed or the quota is raised.
+	ClusterQuotaExceeded OvirtMachineProviderConditionType = "ClusterQuotaExceeded"
+
+	// VMDeletionFailed indicates the VM of the deleted machine couldn't be removed, e.g
+	// while its disks are locked. The machine is kept until the VM is removed, the
+	// message carries the engine error and when the deletion is retried.
+	VMDeletionFailed OvirtMachineProviderConditionType = "VMDeletionFailed"
+
+	// VMRenamed indicates the VM of the machine was renamed in the engine. The VM is
+	// still managed by the ID of the provider ID, the message carries its current name.
+	VMRenamed OvirtMachineProviderConditionType = "VMRenamed"
 )
 
 // OvirtMachineProviderCondition is a condition in a OvirtMachineProviderStatus
@@ -181,9 +755,128 @@ type OvirtMachineProviderCondition struct {
 	Message string `json:"message,omitempty"`
 }
 
+// +genclient
+// +genclient:nonNamespaced
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:scope=Cluster
+// +kubebuilder:subresource:status
+
+// OvirtMachineProviderDefaults is a cluster scoped set of provider spec values,
+// merged by the defaulting webhook into the machines which don't set them.
+// A machine selects its defaults by the DefaultsAnnotation, set on the machine or on
+// its MachineSet, and falls back to the defaults named DefaultsName.
+type OvirtMachineProviderDefaults struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec OvirtMachineProviderDefaultsSpec `json:"spec"`
+	// +optional
+	Status OvirtMachineProviderDefaultsStatus `json:"status,omitempty"`
+}
+
+// OvirtMachineProviderDefaultsSpec holds the default provider spec values
+type OvirtMachineProviderDefaultsSpec struct {
+	// ClusterId is the default oVirt cluster of the VMs.
+	ClusterId string `json:"cluster_id,omitempty"`
+
+	// TemplateName is the default template the VMs are created from.
+	TemplateName string `json:"template_name,omitempty"`
+
+	// StorageDomainId is the default storage domain of the OS disks.
+	StorageDomainId string `json:"storage_domain_id,omitempty"`
+
+	// NetworkInterfaces are the default network interfaces of the VMs.
+	NetworkInterfaces []*NetworkInterface `json:"network_interfaces,omitempty"`
+}
+
+// OvirtMachineProviderDefaultsStatus is the observed state of the defaults
+type OvirtMachineProviderDefaultsStatus struct {
+	// ObservedGeneration is the generation of the spec the conditions were computed from.
+	// +optional
+	ObservedGeneration int64 `json:"observedGeneration,omitempty"`
+
+	// Conditions is a set of conditions associated with the defaults.
+	// +optional
+	Conditions []OvirtMachineProviderDefaultsCondition `json:"conditions,omitempty"`
+}
+
+// OvirtMachineProviderDefaultsConditionType is a valid value for OvirtMachineProviderDefaultsCondition.Type
+type OvirtMachineProviderDefaultsConditionType string
+
+const (
+	// DefaultsValid indicates whether the engine objects referenced by the defaults exist.
+	DefaultsValid OvirtMachineProviderDefaultsConditionType = "Valid"
+)
+
+// OvirtMachineProviderDefaultsCondition is a condition of the defaults
+type OvirtMachineProviderDefaultsCondition struct {
+	// Type is the type of the condition.
+	Type OvirtMachineProviderDefaultsConditionType `json:"type"`
+	// Status is the status of the condition.
+	Status corev1.ConditionStatus `json:"status"`
+	// LastTransitionTime is the last time the condition transitioned from one status to another.
+	// +optional
+	LastTransitionTime metav1.Time `json:"lastTransitionTime,omitempty"`
+	// Reason is a unique, one-word, CamelCase reason for the condition's last transition.
+	// +optional
+	Reason string `json:"reason,omitempty"`
+	// Message is a human-readable message indicating details about last transition.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
+
+// OvirtMachineProviderDefaultsList is a list of OvirtMachineProviderDefaults
+type OvirtMachineProviderDefaultsList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+
+	Items []OvirtMachineProviderDefaults `json:"items"`
+}
+
+const (
+	// DefaultsAnnotation is the machine or MachineSet annotation naming the OvirtMachineProviderDefaults
+	DefaultsAnnotation = "ovirtproviderconfig.machine.openshift.io/defaults"
+	// DefaultsName is the name of the OvirtMachineProviderDefaults used by machines without the annotation
+	DefaultsName = "default"
+)
+
+// ApplyTo sets the default values on the provider spec fields which aren't set.
+// It returns true if the provider spec was changed.
+func (d *OvirtMachineProviderDefaultsSpec) ApplyTo(spec *OvirtMachineProviderSpec) bool {
+	changed := false
+	if spec.ClusterId == "" && d.ClusterId != "" {
+		spec.ClusterId = d.ClusterId
+		changed = true
+	}
+	if spec.TemplateName == "" && spec.TemplateTag == "" && spec.TemplateID == "" && d.TemplateName != "" {
+		spec.TemplateName = d.TemplateName
+		changed = true
+	}
+	if d.StorageDomainId != "" && (spec.OSDisk == nil ||
+		(spec.OSDisk.StorageDomainId == "" && spec.OSDisk.StorageDomainName == "")) {
+		if spec.OSDisk == nil {
+			spec.OSDisk = &Disk{}
+		}
+		spec.OSDisk.StorageDomainId = d.StorageDomainId
+		changed = true
+	}
+	if len(spec.NetworkInterfaces) == 0 && len(d.NetworkInterfaces) > 0 {
+		for _, nic := range d.NetworkInterfaces {
+			spec.NetworkInterfaces = append(spec.NetworkInterfaces, nic.DeepCopy())
+		}
+		changed = true
+	}
+	return changed
+}
+
 func init() {
 	SchemeBuilder.Register(&OvirtMachineProviderSpec{})
 	SchemeBuilder.Register(&OvirtMachineProviderStatus{})
 	SchemeBuilder.Register(&OvirtClusterProviderSpec{})
 	SchemeBuilder.Register(&OvirtClusterProviderStatus{})
+	SchemeBuilder.Register(&OvirtMachineProviderDefaults{}, &OvirtMachineProviderDefaultsList{})
 }
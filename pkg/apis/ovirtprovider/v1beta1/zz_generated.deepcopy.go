@@ -11,6 +11,7 @@ package v1beta1
 
 import (
 	"k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 )
 
@@ -29,6 +30,36 @@ func (in *CPU) DeepCopy() *CPU {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ConsoleAccess) DeepCopyInto(out *ConsoleAccess) {
+	*out = *in
+	if in.SerialConsole != nil {
+		in, out := &in.SerialConsole, &out.SerialConsole
+		*out = new(bool)
+		**out = **in
+	}
+	if in.Graphics != nil {
+		in, out := &in.Graphics, &out.Graphics
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Users != nil {
+		in, out := &in.Users, &out.Users
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ConsoleAccess.
+func (in *ConsoleAccess) DeepCopy() *ConsoleAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(ConsoleAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *Disk) DeepCopyInto(out *Disk) {
 	*out = *in
@@ -44,9 +75,106 @@ func (in *Disk) DeepCopy() *Disk {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GPU) DeepCopyInto(out *GPU) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GPU.
+func (in *GPU) DeepCopy() *GPU {
+	if in == nil {
+		return nil
+	}
+	out := new(GPU)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HostSelector) DeepCopyInto(out *HostSelector) {
+	*out = *in
+	if in.Tags != nil {
+		in, out := &in.Tags, &out.Tags
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HostSelector.
+func (in *HostSelector) DeepCopy() *HostSelector {
+	if in == nil {
+		return nil
+	}
+	out := new(HostSelector)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NUMANode) DeepCopyInto(out *NUMANode) {
+	*out = *in
+	if in.Cores != nil {
+		in, out := &in.Cores, &out.Cores
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+	if in.HostNodes != nil {
+		in, out := &in.HostNodes, &out.HostNodes
+		*out = make([]int32, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NUMANode.
+func (in *NUMANode) DeepCopy() *NUMANode {
+	if in == nil {
+		return nil
+	}
+	out := new(NUMANode)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkConfiguration) DeepCopyInto(out *NetworkConfiguration) {
+	*out = *in
+	if in.Nics != nil {
+		in, out := &in.Nics, &out.Nics
+		*out = make([]NicConfiguration, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSServers != nil {
+		in, out := &in.DNSServers, &out.DNSServers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.DNSSearch != nil {
+		in, out := &in.DNSSearch, &out.DNSSearch
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkConfiguration.
+func (in *NetworkConfiguration) DeepCopy() *NetworkConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
 	*out = *in
+	if in.NetworkFilterParameters != nil {
+		in, out := &in.NetworkFilterParameters, &out.NetworkFilterParameters
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
@@ -59,6 +187,21 @@ func (in *NetworkInterface) DeepCopy() *NetworkInterface {
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NicConfiguration) DeepCopyInto(out *NicConfiguration) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NicConfiguration.
+func (in *NicConfiguration) DeepCopy() *NicConfiguration {
+	if in == nil {
+		return nil
+	}
+	out := new(NicConfiguration)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OvirtClusterProviderSpec) DeepCopyInto(out *OvirtClusterProviderSpec) {
 	*out = *in
@@ -136,6 +279,129 @@ func (in *OvirtMachineProviderCondition) DeepCopy() *OvirtMachineProviderConditi
 	return out
 }
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OvirtMachineProviderDefaults) DeepCopyInto(out *OvirtMachineProviderDefaults) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderDefaults.
+func (in *OvirtMachineProviderDefaults) DeepCopy() *OvirtMachineProviderDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(OvirtMachineProviderDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OvirtMachineProviderDefaults) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OvirtMachineProviderDefaultsCondition) DeepCopyInto(out *OvirtMachineProviderDefaultsCondition) {
+	*out = *in
+	in.LastTransitionTime.DeepCopyInto(&out.LastTransitionTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderDefaultsCondition.
+func (in *OvirtMachineProviderDefaultsCondition) DeepCopy() *OvirtMachineProviderDefaultsCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(OvirtMachineProviderDefaultsCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OvirtMachineProviderDefaultsList) DeepCopyInto(out *OvirtMachineProviderDefaultsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]OvirtMachineProviderDefaults, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderDefaultsList.
+func (in *OvirtMachineProviderDefaultsList) DeepCopy() *OvirtMachineProviderDefaultsList {
+	if in == nil {
+		return nil
+	}
+	out := new(OvirtMachineProviderDefaultsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OvirtMachineProviderDefaultsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OvirtMachineProviderDefaultsSpec) DeepCopyInto(out *OvirtMachineProviderDefaultsSpec) {
+	*out = *in
+	if in.NetworkInterfaces != nil {
+		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
+		*out = make([]*NetworkInterface, len(*in))
+		for i := range *in {
+			if (*in)[i] != nil {
+				in, out := &(*in)[i], &(*out)[i]
+				*out = new(NetworkInterface)
+				(*in).DeepCopyInto(*out)
+			}
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderDefaultsSpec.
+func (in *OvirtMachineProviderDefaultsSpec) DeepCopy() *OvirtMachineProviderDefaultsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OvirtMachineProviderDefaultsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OvirtMachineProviderDefaultsStatus) DeepCopyInto(out *OvirtMachineProviderDefaultsStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]OvirtMachineProviderDefaultsCondition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderDefaultsStatus.
+func (in *OvirtMachineProviderDefaultsStatus) DeepCopy() *OvirtMachineProviderDefaultsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OvirtMachineProviderDefaultsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *OvirtMachineProviderSpec) DeepCopyInto(out *OvirtMachineProviderSpec) {
 	*out = *in
@@ -156,11 +422,26 @@ func (in *OvirtMachineProviderSpec) DeepCopyInto(out *OvirtMachineProviderSpec)
 		*out = new(CPU)
 		**out = **in
 	}
+	if in.Ballooning != nil {
+		in, out := &in.Ballooning, &out.Ballooning
+		*out = new(bool)
+		**out = **in
+	}
 	if in.OSDisk != nil {
 		in, out := &in.OSDisk, &out.OSDisk
 		*out = new(Disk)
 		**out = **in
 	}
+	if in.TemplateDisks != nil {
+		in, out := &in.TemplateDisks, &out.TemplateDisks
+		*out = make([]TemplateDisk, len(*in))
+		copy(*out, *in)
+	}
+	if in.Clone != nil {
+		in, out := &in.Clone, &out.Clone
+		*out = new(bool)
+		**out = **in
+	}
 	if in.NetworkInterfaces != nil {
 		in, out := &in.NetworkInterfaces, &out.NetworkInterfaces
 		*out = make([]*NetworkInterface, len(*in))
@@ -168,10 +449,94 @@ func (in *OvirtMachineProviderSpec) DeepCopyInto(out *OvirtMachineProviderSpec)
 			if (*in)[i] != nil {
 				in, out := &(*in)[i], &(*out)[i]
 				*out = new(NetworkInterface)
-				**out = **in
+				(*in).DeepCopyInto(*out)
 			}
 		}
 	}
+	if in.NetworkConfiguration != nil {
+		in, out := &in.NetworkConfiguration, &out.NetworkConfiguration
+		*out = new(NetworkConfiguration)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AffinityGroupsNames != nil {
+		in, out := &in.AffinityGroupsNames, &out.AffinityGroupsNames
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AffinityLabels != nil {
+		in, out := &in.AffinityLabels, &out.AffinityLabels
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PreferredHosts != nil {
+		in, out := &in.PreferredHosts, &out.PreferredHosts
+		*out = new(HostSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.PlacementHosts != nil {
+		in, out := &in.PlacementHosts, &out.PlacementHosts
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Lease != nil {
+		in, out := &in.Lease, &out.Lease
+		*out = new(VMLease)
+		**out = **in
+	}
+	if in.ShutdownTimeout != nil {
+		in, out := &in.ShutdownTimeout, &out.ShutdownTimeout
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.Console != nil {
+		in, out := &in.Console, &out.Console
+		*out = new(ConsoleAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.GPU != nil {
+		in, out := &in.GPU, &out.GPU
+		*out = new(GPU)
+		**out = **in
+	}
+	if in.RNG != nil {
+		in, out := &in.RNG, &out.RNG
+		*out = new(RNGDevice)
+		**out = **in
+	}
+	if in.SerialNumber != nil {
+		in, out := &in.SerialNumber, &out.SerialNumber
+		*out = new(SerialNumber)
+		**out = **in
+	}
+	if in.CustomProperties != nil {
+		in, out := &in.CustomProperties, &out.CustomProperties
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.CPUPinning != nil {
+		in, out := &in.CPUPinning, &out.CPUPinning
+		*out = make([]VCPUPin, len(*in))
+		copy(*out, *in)
+	}
+	if in.NUMANodes != nil {
+		in, out := &in.NUMANodes, &out.NUMANodes
+		*out = make([]NUMANode, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.MultiQueuesEnabled != nil {
+		in, out := &in.MultiQueuesEnabled, &out.MultiQueuesEnabled
+		*out = new(bool)
+		**out = **in
+	}
+	if in.BootDevices != nil {
+		in, out := &in.BootDevices, &out.BootDevices
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderSpec.
@@ -214,6 +579,18 @@ func (in *OvirtMachineProviderStatus) DeepCopyInto(out *OvirtMachineProviderStat
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
+	if in.CloneStartTime != nil {
+		in, out := &in.CloneStartTime, &out.CloneStartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.DeleteStartTime != nil {
+		in, out := &in.DeleteStartTime, &out.DeleteStartTime
+		*out = (*in).DeepCopy()
+	}
 }
 
 // DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OvirtMachineProviderStatus.
@@ -233,3 +610,78 @@ func (in *OvirtMachineProviderStatus) DeepCopyObject() runtime.Object {
 	}
 	return nil
 }
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *RNGDevice) DeepCopyInto(out *RNGDevice) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new RNGDevice.
+func (in *RNGDevice) DeepCopy() *RNGDevice {
+	if in == nil {
+		return nil
+	}
+	out := new(RNGDevice)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *SerialNumber) DeepCopyInto(out *SerialNumber) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new SerialNumber.
+func (in *SerialNumber) DeepCopy() *SerialNumber {
+	if in == nil {
+		return nil
+	}
+	out := new(SerialNumber)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TemplateDisk) DeepCopyInto(out *TemplateDisk) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TemplateDisk.
+func (in *TemplateDisk) DeepCopy() *TemplateDisk {
+	if in == nil {
+		return nil
+	}
+	out := new(TemplateDisk)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VCPUPin) DeepCopyInto(out *VCPUPin) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VCPUPin.
+func (in *VCPUPin) DeepCopy() *VCPUPin {
+	if in == nil {
+		return nil
+	}
+	out := new(VCPUPin)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMLease) DeepCopyInto(out *VMLease) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMLease.
+func (in *VMLease) DeepCopy() *VMLease {
+	if in == nil {
+		return nil
+	}
+	out := new(VMLease)
+	in.DeepCopyInto(out)
+	return out
+}
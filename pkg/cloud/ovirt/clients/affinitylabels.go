@@ -0,0 +1,83 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// handleAffinityLabels assigns the affinity labels of the spec to the VM, skipping the
+// labels it already has, so the step can be run again after an interrupted creation.
+// The affinity labels are global to the engine, the missing ones are created only if
+// the spec asks for it.
+func (is *InstanceService) handleAffinityLabels(vmService *ovirtsdk.VmService, vm *ovirtsdk.Vm, spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if len(spec.AffinityLabels) == 0 {
+		return nil
+	}
+	labels, err := is.getAffinityLabels(spec.AffinityLabels, spec.CreateMissingAffinityLabels)
+	if err != nil {
+		return err
+	}
+	res, err := vmService.AffinityLabelsService().List().Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed listing the affinity labels of VM %s", vm.MustName())
+	}
+	assigned := make(map[string]bool)
+	for _, label := range res.MustLabel().Slice() {
+		assigned[label.MustId()] = true
+	}
+	for _, label := range labels {
+		if assigned[label.MustId()] {
+			continue
+		}
+		klog.Infof("Adding machine %v to affinity label %v", vm.MustName(), label.MustName())
+		_, err := vmService.AffinityLabelsService().Add().
+			Label(ovirtsdk.NewAffinityLabelBuilder().Id(label.MustId()).MustBuild()).Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed adding VM %s to affinity label %s", vm.MustName(), label.MustName())
+		}
+	}
+	return nil
+}
+
+// getAffinityLabels returns the affinity labels of the names, in the same order.
+// A missing label is created if create is set, else it is an error.
+func (is *InstanceService) getAffinityLabels(names []string, create bool) ([]*ovirtsdk.AffinityLabel, error) {
+	labelsService := is.Connection.SystemService().AffinityLabelsService()
+	res, err := labelsService.List().Send()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed listing the affinity labels")
+	}
+	byName := make(map[string]*ovirtsdk.AffinityLabel)
+	for _, label := range res.MustLabels().Slice() {
+		byName[label.MustName()] = label
+	}
+	labels := make([]*ovirtsdk.AffinityLabel, 0, len(names))
+	for _, name := range names {
+		label, ok := byName[name]
+		if !ok {
+			if !create {
+				return nil, fmt.Errorf("affinity label %s was not found", name)
+			}
+			klog.Infof("Creating affinity label %s", name)
+			addRes, err := labelsService.Add().
+				Label(ovirtsdk.NewAffinityLabelBuilder().Name(name).MustBuild()).Send()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed creating affinity label %s", name)
+			}
+			label = addRes.MustLabel()
+			byName[name] = label
+		}
+		labels = append(labels, label)
+	}
+	return labels, nil
+}
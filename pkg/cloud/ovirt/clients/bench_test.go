@@ -0,0 +1,131 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"testing"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+)
+
+// reportCalls reports the engine calls and the API server reads per benchmark operation
+func reportCalls(b *testing.B, engine *fakeEngine, c *countingClient) {
+	b.ReportMetric(float64(engine.callCount())/float64(b.N), "engine-calls/op")
+	b.ReportMetric(float64(c.readCount())/float64(b.N), "api-reads/op")
+}
+
+// resetCalls zeroes the engine calls and the API server reads counted so far
+func resetCalls(engine *fakeEngine, c *countingClient) {
+	engine.mu.Lock()
+	engine.calls = 0
+	engine.mu.Unlock()
+	c.mu.Lock()
+	c.reads = 0
+	c.mu.Unlock()
+}
+
+// BenchmarkConnectionPoolGet leases the pooled connection of a single secret, every
+// lease tests the connection against the engine
+func BenchmarkConnectionPoolGet(b *testing.B) {
+	engine := newFakeEngine(b)
+	c := newFakeClient(credentialsSecret(engine, "ns", "creds"))
+	pool := NewConnectionPool(c, 0, 0)
+	warm, err := pool.Get("ns", "creds")
+	if err != nil {
+		b.Fatal(err)
+	}
+	pool.Release(warm)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	resetCalls(engine, c)
+	for i := 0; i < b.N; i++ {
+		connection, err := pool.Get("ns", "creds")
+		if err != nil {
+			b.Fatal(err)
+		}
+		pool.Release(connection)
+	}
+	b.StopTimer()
+	reportCalls(b, engine, c)
+}
+
+// BenchmarkConnectionPoolGetParallel leases the connections of more secrets than the
+// pool holds from concurrent callers, so the connections are evicted and built again
+func BenchmarkConnectionPoolGetParallel(b *testing.B) {
+	engine := newFakeEngine(b)
+	var objs []runtime.Object
+	for i := 0; i < 2*DefaultConnectionPoolSize; i++ {
+		objs = append(objs, credentialsSecret(engine, "ns", fmt.Sprintf("creds-%d", i)))
+	}
+	c := newFakeClient(objs...)
+	pool := NewConnectionPool(c, 0, 0)
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for i := 0; pb.Next(); i++ {
+			connection, err := pool.Get("ns", fmt.Sprintf("creds-%d", i%len(objs)))
+			if err != nil {
+				b.Error(err)
+				return
+			}
+			pool.Release(connection)
+		}
+	})
+	b.StopTimer()
+	reportCalls(b, engine, c)
+}
+
+// BenchmarkGetVm fetches the VM of a machine by its provider ID, and by its name
+// for a machine which wasn't provisioned yet
+func BenchmarkGetVm(b *testing.B) {
+	engine := newFakeEngine(b)
+	engine.addVM("vm-0", "worker-0")
+	c := newFakeClient(credentialsSecret(engine, "ns", "creds"))
+	connection, err := NewConnectionPool(c, 0, 0).Get("ns", "creds")
+	if err != nil {
+		b.Fatal(err)
+	}
+	providerID := ovirt.ProviderIDPrefix + "vm-0"
+
+	for _, bc := range []struct {
+		name    string
+		machine machinev1.Machine
+	}{
+		{"ByID", machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{Name: "worker-0"},
+			Spec:       machinev1.MachineSpec{ProviderID: &providerID},
+		}},
+		{"ByName", machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}},
+	} {
+		b.Run(bc.name, func(b *testing.B) {
+			is := &InstanceService{Connection: connection, MachineName: bc.machine.Name}
+			b.ReportAllocs()
+			b.ResetTimer()
+			resetCalls(engine, c)
+			for i := 0; i < b.N; i++ {
+				instance, err := is.GetVm(bc.machine)
+				if err != nil {
+					b.Fatal(err)
+				}
+				if instance == nil {
+					b.Fatalf("VM of machine %s not found", bc.machine.Name)
+				}
+			}
+			b.StopTimer()
+			reportCalls(b, engine, c)
+		})
+	}
+}
@@ -0,0 +1,92 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+const (
+	// CPUTypeLabel is the node label holding the CPU type of the oVirt cluster of the VM,
+	// e.g "Secure-Intel-Cascadelake-Server-Family"
+	CPUTypeLabel = "ovirt.machine.openshift.io/cpu-type"
+	// CPULevelLabel is the node label holding the level of the CPU type, a higher level
+	// is a newer CPU generation of the same vendor, so it can be matched with the Gt operator
+	CPULevelLabel = "ovirt.machine.openshift.io/cpu-level"
+)
+
+var invalidLabelValueChars = regexp.MustCompile(`[^A-Za-z0-9_.-]+`)
+
+// ClusterCPU is the CPU type the VMs of an oVirt cluster run with
+type ClusterCPU struct {
+	Type string
+	// Level orders the CPU types of an architecture by generation, 0 if unknown
+	Level int64
+}
+
+// GetClusterCPU returns the CPU type of the cluster, with its level in the
+// compatibility version of the cluster
+func (is *InstanceService) GetClusterCPU(clusterID string) (*ClusterCPU, error) {
+	res, err := is.Connection.SystemService().ClustersService().ClusterService(clusterID).Get().Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching cluster %s", clusterID)
+	}
+	cluster := res.MustCluster()
+	cpu, ok := cluster.Cpu()
+	if !ok {
+		return nil, fmt.Errorf("cluster %s has no CPU type", clusterID)
+	}
+	cpuType, ok := cpu.Type()
+	if !ok {
+		return nil, fmt.Errorf("cluster %s has no CPU type", clusterID)
+	}
+	clusterCPU := &ClusterCPU{Type: cpuType}
+
+	version, ok := cluster.Version()
+	if !ok {
+		return clusterCPU, nil
+	}
+	levelID := fmt.Sprintf("%d.%d", version.MustMajor(), version.MustMinor())
+	levelRes, err := is.Connection.SystemService().ClusterLevelsService().LevelService(levelID).Get().Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching cluster level %s", levelID)
+	}
+	if types, ok := levelRes.MustLevel().CpuTypes(); ok {
+		for _, t := range types.Slice() {
+			if name, ok := t.Name(); ok && name == cpuType {
+				clusterCPU.Level, _ = t.Level()
+			}
+		}
+	}
+	return clusterCPU, nil
+}
+
+// NodeLabels returns the node labels describing the CPU
+func (c *ClusterCPU) NodeLabels() map[string]string {
+	labels := map[string]string{}
+	if value := labelValue(c.Type); value != "" {
+		labels[CPUTypeLabel] = value
+	}
+	if c.Level > 0 {
+		labels[CPULevelLabel] = strconv.FormatInt(c.Level, 10)
+	}
+	return labels
+}
+
+// labelValue turns s into a valid label value, replacing the invalid characters by dashes
+func labelValue(s string) string {
+	value := invalidLabelValueChars.ReplaceAllString(s, "-")
+	if len(value) > validation.LabelValueMaxLength {
+		value = value[:validation.LabelValueMaxLength]
+	}
+	return strings.Trim(value, "-_.")
+}
@@ -0,0 +1,259 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"container/list"
+	"sync"
+	"time"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/metrics"
+)
+
+const (
+	// DefaultConnectionPoolSize is the default number of engine connections kept in the pool
+	DefaultConnectionPoolSize = 10
+	// DefaultConnectionIdleTimeout is the default time an unused connection is kept in the pool
+	DefaultConnectionIdleTimeout = 30 * time.Minute
+)
+
+// ConnectionPool holds engine connections keyed by the credentials secret they were
+// built from. It is bounded by size, evicting the least recently used connection,
+// and connections which weren't used for the idle timeout are closed.
+// It is safe for concurrent use. Every connection returned by Get must be handed
+// back with Release, an evicted connection is closed only once no reconcile uses it.
+type ConnectionPool struct {
+	client      client.Client
+	size        int
+	idleTimeout time.Duration
+
+	// OnCertificateError is called when the engine certificate stops being trusted by the
+	// connections of a secret, e.g after the engine certificate or its CA was rotated.
+	// It is called once until a connection of the secret works again.
+	OnCertificateError func(secret types.NamespacedName, err error)
+
+	// mu guards the pool state below, it is never held across engine or API calls
+	mu      sync.Mutex
+	lru     *list.List
+	entries map[types.NamespacedName]*list.Element
+	leased  map[*ovirtsdk.Connection]*pooledConnection
+	// certFailing holds the secrets whose connections fail verifying the engine certificate
+	certFailing map[types.NamespacedName]bool
+	// logins serializes testing and building the connection of each secret
+	logins map[types.NamespacedName]*sync.Mutex
+	// closing holds the evicted connections to close once mu is released
+	closing []*pooledConnection
+}
+
+type pooledConnection struct {
+	key        types.NamespacedName
+	connection *ovirtsdk.Connection
+	lastUsed   time.Time
+	// refs counts the callers using the connection
+	refs int
+	// evicted is set once the connection left the pool, it is closed on the last release
+	evicted bool
+}
+
+// NewConnectionPool returns a connection pool reading the credentials secrets with the client
+func NewConnectionPool(client client.Client, size int, idleTimeout time.Duration) *ConnectionPool {
+	if size <= 0 {
+		size = DefaultConnectionPoolSize
+	}
+	if idleTimeout <= 0 {
+		idleTimeout = DefaultConnectionIdleTimeout
+	}
+	return &ConnectionPool{
+		client:      client,
+		size:        size,
+		idleTimeout: idleTimeout,
+		lru:         list.New(),
+		entries:     make(map[types.NamespacedName]*list.Element),
+		leased:      make(map[*ovirtsdk.Connection]*pooledConnection),
+		certFailing: make(map[types.NamespacedName]bool),
+		logins:      make(map[types.NamespacedName]*sync.Mutex),
+	}
+}
+
+// Get returns a working connection for the credentials secret, re-login if the
+// pooled connection expired or its engine endpoint failed, so an engine with several
+// URLs fails over to the next one. A connection failing to verify the engine certificate is
+// rebuilt with the CA sources read again, so a rotated engine certificate doesn't require
+// a restart. The connection must be released once the caller is done.
+// Only callers of the same secret wait for each other while the connection is
+// tested or built.
+func (p *ConnectionPool) Get(namespace, secretName string) (*ovirtsdk.Connection, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: secretName}
+
+	p.mu.Lock()
+	login, ok := p.logins[key]
+	if !ok {
+		login = &sync.Mutex{}
+		p.logins[key] = login
+	}
+	p.unlock()
+
+	login.Lock()
+	defer login.Unlock()
+
+	if connection, ok := p.getPooled(key); ok {
+		return connection, nil
+	}
+
+	creds, err := GetCredentialsSecret(p.client, namespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+	connection, err := NewConnection(creds)
+	if err != nil {
+		if IsCertificateError(err) {
+			p.mu.Lock()
+			p.certificateFailed(key, err)
+			p.unlock()
+		}
+		return nil, err
+	}
+
+	p.mu.Lock()
+	defer p.unlock()
+	if e, ok := p.entries[key]; ok {
+		// only Get adds entries, and it holds the login of the key, keep the pool consistent anyway
+		p.remove(e, "replaced")
+	}
+	entry := &pooledConnection{key: key, connection: connection, lastUsed: time.Now(), refs: 1}
+	p.entries[key] = p.lru.PushFront(entry)
+	p.leased[connection] = entry
+	for p.lru.Len() > p.size {
+		p.remove(p.lru.Back(), "size")
+	}
+	metrics.ConnectionPoolSize.Set(float64(p.lru.Len()))
+	return connection, nil
+}
+
+// getPooled leases the pooled connection of the key if it still works. The
+// connection is leased while it is tested, so it isn't closed if evicted meanwhile.
+func (p *ConnectionPool) getPooled(key types.NamespacedName) (*ovirtsdk.Connection, bool) {
+	p.mu.Lock()
+	p.evictIdle()
+	e, ok := p.entries[key]
+	if !ok {
+		p.unlock()
+		return nil, false
+	}
+	entry := e.Value.(*pooledConnection)
+	entry.refs++
+	p.leased[entry.connection] = entry
+	p.unlock()
+
+	err := entry.connection.Test()
+
+	p.mu.Lock()
+	defer p.unlock()
+	if err == nil {
+		delete(p.certFailing, key)
+		entry.lastUsed = time.Now()
+		if !entry.evicted {
+			p.lru.MoveToFront(e)
+		}
+		return entry.connection, true
+	}
+	if !entry.evicted {
+		if IsCertificateError(err) {
+			p.remove(e, "certificate")
+		} else {
+			// session expired or some other error, re-login.
+			p.remove(e, "expired")
+		}
+	}
+	if IsCertificateError(err) {
+		p.certificateFailed(key, err)
+	}
+	p.release(entry)
+	return nil, false
+}
+
+// Release hands back a connection returned by Get
+func (p *ConnectionPool) Release(connection *ovirtsdk.Connection) {
+	p.mu.Lock()
+	defer p.unlock()
+
+	entry, ok := p.leased[connection]
+	if !ok {
+		return
+	}
+	p.release(entry)
+}
+
+func (p *ConnectionPool) release(entry *pooledConnection) {
+	entry.lastUsed = time.Now()
+	entry.refs--
+	if entry.refs > 0 {
+		return
+	}
+	delete(p.leased, entry.connection)
+	if entry.evicted {
+		p.closing = append(p.closing, entry)
+	}
+}
+
+// unlock releases mu and closes the connections evicted while it was held
+func (p *ConnectionPool) unlock() {
+	closing := p.closing
+	p.closing = nil
+	p.mu.Unlock()
+	for _, entry := range closing {
+		closeConnection(entry)
+	}
+}
+
+// certificateFailed drops the engine CAs fetched by fingerprint, so the next connection
+// trusts the CA sources as they are now, and reports the failure once
+func (p *ConnectionPool) certificateFailed(key types.NamespacedName, err error) {
+	forgetEngineCAs()
+	if p.certFailing[key] {
+		return
+	}
+	p.certFailing[key] = true
+	klog.Warningf("The engine certificate isn't trusted by the connection for secret %s anymore, "+
+		"rebuilding it with the CA sources read again: %v", key, err)
+	if p.OnCertificateError != nil {
+		p.OnCertificateError(key, err)
+	}
+}
+
+// evictIdle closes the connections which weren't used for the idle timeout
+func (p *ConnectionPool) evictIdle() {
+	for e := p.lru.Back(); e != nil; e = p.lru.Back() {
+		entry := e.Value.(*pooledConnection)
+		if entry.refs > 0 || time.Since(entry.lastUsed) < p.idleTimeout {
+			return
+		}
+		p.remove(e, "idle")
+	}
+}
+
+func (p *ConnectionPool) remove(e *list.Element, reason string) {
+	entry := p.lru.Remove(e).(*pooledConnection)
+	delete(p.entries, entry.key)
+	entry.evicted = true
+	if entry.refs == 0 {
+		p.closing = append(p.closing, entry)
+	}
+	metrics.ConnectionPoolEvictions.WithLabelValues(reason).Inc()
+	metrics.ConnectionPoolSize.Set(float64(p.lru.Len()))
+}
+
+func closeConnection(entry *pooledConnection) {
+	if err := entry.connection.Close(); err != nil {
+		klog.V(3).Infof("failed closing the connection for secret %s: %v", entry.key, err)
+	}
+}
@@ -0,0 +1,177 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+)
+
+// poolStep is an operation on the pool: get or release the connection of a secret,
+// or fail or recover the engine
+type poolStep struct {
+	op     string
+	secret string
+}
+
+func getStep(secret string) poolStep     { return poolStep{op: "get", secret: secret} }
+func releaseStep(secret string) poolStep { return poolStep{op: "release", secret: secret} }
+
+var (
+	failEngine    = poolStep{op: "fail"}
+	recoverEngine = poolStep{op: "recover"}
+)
+
+func TestConnectionPool(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		size        int
+		idleTimeout time.Duration
+		steps       []poolStep
+		// wantConnections is the number of distinct connections Get returned
+		wantConnections int
+		// wantPooled are the secrets of the pooled connections, most recently used first
+		wantPooled []string
+		wantLeased int
+		// wantClosed is the number of connections closed
+		wantClosed int
+	}{
+		{
+			name:            "same secret shares a connection",
+			steps:           []poolStep{getStep("a"), getStep("a"), releaseStep("a")},
+			wantConnections: 1,
+			wantPooled:      []string{"a"},
+			wantLeased:      1,
+		},
+		{
+			name:            "released connection is reused",
+			steps:           []poolStep{getStep("a"), releaseStep("a"), getStep("a"), releaseStep("a")},
+			wantConnections: 1,
+			wantPooled:      []string{"a"},
+		},
+		{
+			name:            "full pool evicts the least recently used",
+			size:            2,
+			steps:           []poolStep{getStep("a"), releaseStep("a"), getStep("b"), releaseStep("b"), getStep("a"), releaseStep("a"), getStep("c"), releaseStep("c")},
+			wantConnections: 3,
+			wantPooled:      []string{"c", "a"},
+			wantClosed:      1,
+		},
+		{
+			name:            "evicted leased connection is kept open",
+			size:            1,
+			steps:           []poolStep{getStep("a"), getStep("b")},
+			wantConnections: 2,
+			wantPooled:      []string{"b"},
+			wantLeased:      2,
+		},
+		{
+			name:            "evicted connection is closed on its last release",
+			size:            1,
+			steps:           []poolStep{getStep("a"), getStep("a"), getStep("b"), releaseStep("a"), releaseStep("a")},
+			wantConnections: 2,
+			wantPooled:      []string{"b"},
+			wantLeased:      1,
+			wantClosed:      1,
+		},
+		{
+			name:            "idle connection is evicted",
+			idleTimeout:     time.Nanosecond,
+			steps:           []poolStep{getStep("a"), releaseStep("a"), getStep("b"), releaseStep("b")},
+			wantConnections: 2,
+			wantPooled:      []string{"b"},
+			wantClosed:      1,
+		},
+		{
+			name:            "leased connection isn't idle",
+			idleTimeout:     time.Nanosecond,
+			steps:           []poolStep{getStep("a"), getStep("b")},
+			wantConnections: 2,
+			wantPooled:      []string{"b", "a"},
+			wantLeased:      2,
+		},
+		{
+			name:            "failing connection is rebuilt",
+			steps:           []poolStep{getStep("a"), releaseStep("a"), failEngine, getStep("a"), recoverEngine, releaseStep("a")},
+			wantConnections: 2,
+			wantPooled:      []string{"a"},
+			wantClosed:      1,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := newFakeEngine(t)
+			c := newFakeClient(
+				credentialsSecret(engine, "ns", "a"),
+				credentialsSecret(engine, "ns", "b"),
+				credentialsSecret(engine, "ns", "c"))
+			pool := NewConnectionPool(c, tc.size, tc.idleTimeout)
+
+			leased := make(map[string][]*ovirtsdk.Connection)
+			connections := make(map[*ovirtsdk.Connection]bool)
+			for _, step := range tc.steps {
+				switch step.op {
+				case "get":
+					connection, err := pool.Get("ns", step.secret)
+					if err != nil {
+						t.Fatalf("Get(%s) failed: %v", step.secret, err)
+					}
+					leased[step.secret] = append(leased[step.secret], connection)
+					connections[connection] = true
+				case "release":
+					n := len(leased[step.secret])
+					pool.Release(leased[step.secret][n-1])
+					leased[step.secret] = leased[step.secret][:n-1]
+				case "fail":
+					engine.setFailAPI(true)
+				case "recover":
+					engine.setFailAPI(false)
+				}
+			}
+
+			pool.mu.Lock()
+			var pooled []string
+			for e := pool.lru.Front(); e != nil; e = e.Next() {
+				pooled = append(pooled, e.Value.(*pooledConnection).key.Name)
+			}
+			leasedCount := len(pool.leased)
+			pool.mu.Unlock()
+
+			if len(connections) != tc.wantConnections {
+				t.Errorf("got %d connections, want %d", len(connections), tc.wantConnections)
+			}
+			if !reflect.DeepEqual(pooled, tc.wantPooled) {
+				t.Errorf("pooled %v, want %v", pooled, tc.wantPooled)
+			}
+			if leasedCount != tc.wantLeased {
+				t.Errorf("%d connections leased, want %d", leasedCount, tc.wantLeased)
+			}
+			if closed := engine.logoutCount(); closed != tc.wantClosed {
+				t.Errorf("%d connections closed, want %d", closed, tc.wantClosed)
+			}
+		})
+	}
+}
+
+func TestConnectionPoolReleaseUnknown(t *testing.T) {
+	engine := newFakeEngine(t)
+	pool := NewConnectionPool(newFakeClient(), 0, 0)
+	connection, err := ovirtsdk.NewConnectionBuilder().URL(engine.apiURL()).
+		Username("admin@internal").Password("secret").Insecure(true).Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a connection the pool didn't hand out is ignored
+	pool.Release(connection)
+	if len(pool.leased) != 0 || pool.lru.Len() != 0 {
+		t.Errorf("pool changed by releasing an unknown connection")
+	}
+}
@@ -0,0 +1,167 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// consoleUserRole is the role granting the console users access to the VM consoles.
+// It lets them connect to the consoles without managing the VM.
+const consoleUserRole = "UserRole"
+
+// ValidateConsole checks the graphics protocols of the spec
+func ValidateConsole(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.Console == nil {
+		return nil
+	}
+	protocols := make(map[string]bool, len(spec.Console.Graphics))
+	for _, protocol := range spec.Console.Graphics {
+		switch protocol {
+		case ovirtconfigv1.GraphicsVNC, ovirtconfigv1.GraphicsSPICE, ovirtconfigv1.GraphicsHeadless:
+		default:
+			return fmt.Errorf("graphics protocol must be %s, %s or %s, got %s",
+				ovirtconfigv1.GraphicsVNC, ovirtconfigv1.GraphicsSPICE, ovirtconfigv1.GraphicsHeadless, protocol)
+		}
+		if protocols[protocol] {
+			return fmt.Errorf("graphics protocol %s is listed more than once", protocol)
+		}
+		protocols[protocol] = true
+	}
+	if protocols[ovirtconfigv1.GraphicsHeadless] && len(protocols) > 1 {
+		return fmt.Errorf("a headless VM can't have graphics protocols")
+	}
+	return nil
+}
+
+// handleGraphicsConsoles replaces the graphics consoles inherited from the template with
+// the ones of the spec. The consoles which already match the spec are kept.
+func (is *InstanceService) handleGraphicsConsoles(vmService *ovirtsdk.VmService, spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.Console == nil || len(spec.Console.Graphics) == 0 {
+		return nil
+	}
+	desired := make(map[ovirtsdk.GraphicsType]bool)
+	for _, protocol := range spec.Console.Graphics {
+		if protocol != ovirtconfigv1.GraphicsHeadless {
+			desired[ovirtsdk.GraphicsType(protocol)] = true
+		}
+	}
+	consolesService := vmService.GraphicsConsolesService()
+	res, err := consolesService.List().Send()
+	if err != nil {
+		return errors.Wrap(err, "failed listing the VM graphics consoles")
+	}
+	present := make(map[ovirtsdk.GraphicsType]bool)
+	for _, console := range res.MustConsoles().Slice() {
+		protocol, _ := console.Protocol()
+		if desired[protocol] {
+			present[protocol] = true
+			continue
+		}
+		if _, err := consolesService.ConsoleService(console.MustId()).Remove().Send(); err != nil {
+			return errors.Wrapf(err, "failed removing the %s graphics console", protocol)
+		}
+	}
+	for _, name := range spec.Console.Graphics {
+		protocol := ovirtsdk.GraphicsType(name)
+		if !desired[protocol] {
+			continue
+		}
+		if present[protocol] {
+			klog.V(5).Infof("Graphics console %s already exists, skipping", protocol)
+			continue
+		}
+		_, err := consolesService.Add().
+			Console(ovirtsdk.NewGraphicsConsoleBuilder().Protocol(protocol).MustBuild()).
+			Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed adding the %s graphics console", protocol)
+		}
+	}
+	return nil
+}
+
+// handleConsoleAccess grants the console users of the spec the console role on the VM,
+// skipping the users which already have it.
+func (is *InstanceService) handleConsoleAccess(vmService *ovirtsdk.VmService, spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.Console == nil || len(spec.Console.Users) == 0 {
+		return nil
+	}
+	role, err := is.getRole(consoleUserRole)
+	if err != nil {
+		return err
+	}
+	permissions, err := vmService.PermissionsService().List().Send()
+	if err != nil {
+		return errors.Wrap(err, "failed listing the VM permissions")
+	}
+	granted := make(map[string]bool)
+	for _, p := range permissions.MustPermissions().Slice() {
+		user, hasUser := p.User()
+		r, hasRole := p.Role()
+		if hasUser && hasRole && r.MustId() == role.MustId() {
+			granted[user.MustId()] = true
+		}
+	}
+
+	for _, name := range spec.Console.Users {
+		user, err := is.getUser(name)
+		if err != nil {
+			return err
+		}
+		if granted[user.MustId()] {
+			klog.V(5).Infof("User %s already has console access, skipping", name)
+			continue
+		}
+		_, err = vmService.PermissionsService().Add().Permission(
+			ovirtsdk.NewPermissionBuilder().
+				Role(role).
+				UserBuilder(ovirtsdk.NewUserBuilder().Id(user.MustId())).
+				MustBuild()).
+			Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed granting console access to user %s", name)
+		}
+	}
+	return nil
+}
+
+func (is *InstanceService) getRole(name string) (*ovirtsdk.Role, error) {
+	res, err := is.Connection.SystemService().RolesService().List().Send()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed listing roles")
+	}
+	for _, r := range res.MustRoles().Slice() {
+		if n, ok := r.Name(); ok && n == name {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("role %s was not found", name)
+}
+
+// getUser returns the user by its name, in the user@domain form
+func (is *InstanceService) getUser(name string) (*ovirtsdk.User, error) {
+	res, err := is.Connection.SystemService().UsersService().List().Search("usrname=" + searchValue(name)).Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed searching user %s", name)
+	}
+	for _, u := range res.MustUsers().Slice() {
+		if n, ok := u.UserName(); ok && n == name {
+			return u, nil
+		}
+		if p, ok := u.Principal(); ok && p == name {
+			return u, nil
+		}
+	}
+	return nil, fmt.Errorf("user %s was not found", name)
+}
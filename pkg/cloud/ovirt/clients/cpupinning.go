@@ -0,0 +1,84 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidateCPUPinning checks the auto pinning policy and the vCPUs pinning of the spec
+func ValidateCPUPinning(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	switch spec.AutoPinningPolicy {
+	case "", ovirtconfigv1.AutoPinningPolicyNone, ovirtconfigv1.AutoPinningPolicyResizeAndPin:
+	default:
+		return fmt.Errorf("auto pinning policy must be %s or %s, got %s",
+			ovirtconfigv1.AutoPinningPolicyNone, ovirtconfigv1.AutoPinningPolicyResizeAndPin, spec.AutoPinningPolicy)
+	}
+	if len(spec.CPUPinning) > 0 && spec.AutoPinningPolicy == ovirtconfigv1.AutoPinningPolicyResizeAndPin {
+		return fmt.Errorf("cpu pinning can't be used with the %s auto pinning policy",
+			ovirtconfigv1.AutoPinningPolicyResizeAndPin)
+	}
+	if cpuPinned(spec) && !hostsSelected(spec) {
+		return fmt.Errorf("a VM with pinned CPUs must be pinned to hosts, placement or preferred hosts are required")
+	}
+	pinned := make(map[int32]bool, len(spec.CPUPinning))
+	for _, pin := range spec.CPUPinning {
+		if pin.VCPU < 0 {
+			return fmt.Errorf("invalid vcpu %d", pin.VCPU)
+		}
+		if pin.CPUSet == "" {
+			return fmt.Errorf("the cpu set of vcpu %d is empty", pin.VCPU)
+		}
+		if pinned[pin.VCPU] {
+			return fmt.Errorf("vcpu %d is pinned more than once", pin.VCPU)
+		}
+		pinned[pin.VCPU] = true
+	}
+	return nil
+}
+
+// hostPinned returns true if the VM must be pinned to its hosts, which the engine
+// requires to pin the VM CPUs or NUMA nodes to the host ones
+func hostPinned(spec *ovirtconfigv1.OvirtMachineProviderSpec) bool {
+	if cpuPinned(spec) {
+		return true
+	}
+	for _, node := range spec.NUMANodes {
+		if len(node.HostNodes) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// cpuPinned returns true if the VM CPUs are pinned to the host CPUs
+func cpuPinned(spec *ovirtconfigv1.OvirtMachineProviderSpec) bool {
+	return len(spec.CPUPinning) > 0 || spec.AutoPinningPolicy == ovirtconfigv1.AutoPinningPolicyResizeAndPin
+}
+
+// vcpuPins returns the engine vCPUs pinning of the spec
+func vcpuPins(pinning []ovirtconfigv1.VCPUPin) []*ovirtsdk.VcpuPin {
+	pins := make([]*ovirtsdk.VcpuPin, 0, len(pinning))
+	for _, pin := range pinning {
+		pins = append(pins, ovirtsdk.NewVcpuPinBuilder().
+			Vcpu(int64(pin.VCPU)).
+			CpuSet(pin.CPUSet).
+			MustBuild())
+	}
+	return pins
+}
+
+// autoPinningPolicy maps the spec auto pinning policy to the engine one
+func autoPinningPolicy(policy string) ovirtsdk.AutoPinningPolicy {
+	if policy == ovirtconfigv1.AutoPinningPolicyResizeAndPin {
+		return ovirtsdk.AUTOPINNINGPOLICY_ADJUST
+	}
+	return ovirtsdk.AUTOPINNINGPOLICY_DISABLED
+}
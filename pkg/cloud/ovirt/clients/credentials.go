@@ -8,8 +8,8 @@ package clients
 import (
 	"context"
 	"fmt"
-	"io"
 	"io/ioutil"
+	"net/url"
 	"strconv"
 	"strings"
 
@@ -17,6 +17,8 @@ import (
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/klog"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
 )
 
 type OvirtCreds struct {
@@ -26,6 +28,10 @@ type OvirtCreds struct {
 	CAFile   string
 	Insecure bool
 	CABundle string
+	// CAFingerprint is the SHA-256 fingerprint of the engine CA certificate. When set,
+	// the CA certificate is fetched from the engine pki-resource endpoint and trusted
+	// if it matches, for setups where the CA isn't distributed yet.
+	CAFingerprint string
 }
 
 func GetCredentialsSecret(coreClient client.Client, namespace string, secretName string) (*OvirtCreds, error) {
@@ -50,34 +56,138 @@ func GetCredentialsSecret(coreClient client.Client, namespace string, secretName
 	}
 	o.Insecure = insecure
 	o.CABundle = string(credentialsSecret.Data["ovirt_ca_bundle"])
+	if o.CABundle == "" {
+		o.CABundle = string(credentialsSecret.Data["ca_bundle"])
+	}
+	o.CAFingerprint = string(credentialsSecret.Data["ovirt_ca_fingerprint"])
+	return &o, nil
+}
+
+// engineAPIPath is the path of the engine API, used when an URL of the credentials has no path
+const engineAPIPath = "/ovirt-engine/api"
 
-	// write CA bundle to a file if exist.
-	// its best if we could mount the secret into a file,
-	// but this controller deployment cannot
-	if o.CABundle != "" {
-		caFilePath, err := writeCA(strings.NewReader(o.CABundle))
+var (
+	// systemCABundleFiles are the system trust stores, the first existing one is used
+	systemCABundleFiles = []string{
+		"/etc/pki/tls/certs/ca-bundle.crt",
+		"/etc/ssl/certs/ca-certificates.crt",
+	}
+	// proxyCABundleFile is where OpenShift injects the cluster proxy trust bundle
+	proxyCABundleFile = "/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem"
+)
+
+// URLs returns the engine API URLs of the credentials. The ovirt_url key may list
+// several comma separated URLs, e.g the endpoints of an engine behind HA reverse
+// proxies, which are tried in order. An URL without a path gets the default API path.
+func (c *OvirtCreds) URLs() ([]string, error) {
+	var urls []string
+	for _, s := range strings.Split(c.URL, ",") {
+		s = strings.TrimSpace(s)
+		if s == "" {
+			continue
+		}
+		u, err := url.Parse(s)
 		if err != nil {
-			klog.Errorf("failed to extract and store the CA %s", err)
-			return nil, err
+			return nil, fmt.Errorf("invalid engine URL %q: %v", s, err)
+		}
+		if (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, fmt.Errorf("invalid engine URL %q, expected http(s)://host[:port][/path]", s)
 		}
-		o.CAFile = caFilePath
+		if u.Path == "" || u.Path == "/" {
+			u.Path = engineAPIPath
+		}
+		urls = append(urls, u.String())
 	}
-	return &o, nil
+	if len(urls) == 0 {
+		return nil, fmt.Errorf("the credentials have no engine URL")
+	}
+	return urls, nil
 }
 
-func writeCA(source io.Reader) (string, error) {
-	f, err := ioutil.TempFile("", "ovirt-ca-bundle")
+// NewConnection returns a connection to the oVirt engine API built from the credentials.
+// The engine certificate is verified with the merged trust store of trustBundle.
+// When the credentials list several URLs, the first one which accepts the login is used.
+func NewConnection(creds *OvirtCreds) (*ovirtsdk.Connection, error) {
+	urls, err := creds.URLs()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer f.Close()
-	content, err := ioutil.ReadAll(source)
-	if err != nil {
-		return "", err
+	if len(urls) == 1 {
+		return newConnection(creds, urls[0])
 	}
-	_, err = f.Write(content)
-	if err != nil {
-		return "", err
+	var failures []string
+	for _, u := range urls {
+		connection, err := newConnection(creds, u)
+		if err == nil {
+			err = connection.Test()
+			if err == nil {
+				return connection, nil
+			}
+			_ = connection.Close()
+		}
+		klog.Warningf("Failed connecting to the engine at %s, trying the next URL: %v", u, err)
+		failures = append(failures, fmt.Sprintf("%s: %v", u, err))
+	}
+	return nil, fmt.Errorf("failed connecting to the engine at any of its URLs: %s", strings.Join(failures, "; "))
+}
+
+func newConnection(creds *OvirtCreds, engineURL string) (*ovirtsdk.Connection, error) {
+	builder := ovirtsdk.NewConnectionBuilder().
+		URL(engineURL).
+		Username(creds.Username).
+		Password(creds.Password).
+		Insecure(creds.Insecure)
+	if !creds.Insecure {
+		bundle, err := trustBundle(creds, engineURL)
+		if err != nil {
+			return nil, err
+		}
+		if len(bundle) > 0 {
+			builder.CACert(bundle)
+		}
+	}
+	return builder.Build()
+}
+
+// trustBundle returns the PEM trust store verifying the engine certificate: the
+// system CAs, the cluster proxy trust bundle, and the CA file and CA bundle of the
+// credentials, so an engine signed by an intermediate enterprise CA is trusted
+// whichever of them holds the chain. With a CA fingerprint, the CA fetched from the
+// engine is added as well.
+func trustBundle(creds *OvirtCreds, engineURL string) ([]byte, error) {
+	var bundle []byte
+	for _, file := range systemCABundleFiles {
+		if pem, err := ioutil.ReadFile(file); err == nil {
+			bundle = appendPEM(bundle, pem)
+			break
+		}
+	}
+	if pem, err := ioutil.ReadFile(proxyCABundleFile); err == nil {
+		bundle = appendPEM(bundle, pem)
+	}
+	if creds.CAFile != "" {
+		pem, err := ioutil.ReadFile(creds.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed reading the CA file %s: %v", creds.CAFile, err)
+		}
+		bundle = appendPEM(bundle, pem)
+	}
+	if creds.CABundle != "" {
+		bundle = appendPEM(bundle, []byte(creds.CABundle))
+	}
+	if creds.CAFingerprint != "" {
+		pem, err := fetchEngineCA(engineURL, creds.CAFingerprint)
+		if err != nil {
+			return nil, err
+		}
+		bundle = appendPEM(bundle, pem)
+	}
+	return bundle, nil
+}
+
+func appendPEM(bundle, pem []byte) []byte {
+	if len(bundle) > 0 && bundle[len(bundle)-1] != '\n' {
+		bundle = append(bundle, '\n')
 	}
-	return f.Name(), nil
+	return append(bundle, pem...)
 }
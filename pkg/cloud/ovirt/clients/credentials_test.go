@@ -0,0 +1,85 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOvirtCredsURLs(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		url     string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "full URL",
+			url:  "https://engine.example.com/ovirt-engine/api",
+			want: []string{"https://engine.example.com/ovirt-engine/api"},
+		},
+		{
+			name: "default API path",
+			url:  "https://engine.example.com",
+			want: []string{"https://engine.example.com/ovirt-engine/api"},
+		},
+		{
+			name: "default API path after slash",
+			url:  "https://engine.example.com:8443/",
+			want: []string{"https://engine.example.com:8443/ovirt-engine/api"},
+		},
+		{
+			name: "custom path kept",
+			url:  "http://proxy.example.com/engine/api",
+			want: []string{"http://proxy.example.com/engine/api"},
+		},
+		{
+			name: "several URLs in order",
+			url:  " https://a.example.com , https://b.example.com/ovirt-engine/api,, ",
+			want: []string{"https://a.example.com/ovirt-engine/api", "https://b.example.com/ovirt-engine/api"},
+		},
+		{
+			name:    "empty",
+			url:     " , ",
+			wantErr: true,
+		},
+		{
+			name:    "unsupported scheme",
+			url:     "ftp://engine.example.com/ovirt-engine/api",
+			wantErr: true,
+		},
+		{
+			name:    "no host",
+			url:     "https:///ovirt-engine/api",
+			wantErr: true,
+		},
+		{
+			name:    "unparsable",
+			url:     "https://engine.example.com/%zz",
+			wantErr: true,
+		},
+		{
+			name:    "one invalid URL among valid ones",
+			url:     "https://a.example.com,engine.example.com",
+			wantErr: true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			creds := &OvirtCreds{URL: tc.url}
+			got, err := creds.URLs()
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("URLs() error = %v, want error %v", err, tc.wantErr)
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("URLs() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,89 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+const (
+	// mdevTypeProperty is the VM custom property listing the mediated devices of the VM
+	mdevTypeProperty = "mdev_type"
+	// mdevNoDisplay disables the display of the mediated devices
+	mdevNoDisplay = "nodisplay"
+	// hugepagesProperty is the VM custom property setting the size of the hugepages backing the VM memory
+	hugepagesProperty = "hugepages"
+)
+
+// customPropertyName matches the names the engine accepts for VM custom properties
+var customPropertyName = regexp.MustCompile(`^\w+$`)
+
+// ValidateCustomProperties checks the names of the custom properties of the spec, and
+// that they don't set the properties the provider sets for other fields
+func ValidateCustomProperties(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	for name := range spec.CustomProperties {
+		if !customPropertyName.MatchString(name) {
+			return fmt.Errorf("invalid custom property name %q", name)
+		}
+		switch name {
+		case mdevTypeProperty:
+			return fmt.Errorf("custom property %s is set by the GPU of the VM", name)
+		case hugepagesProperty:
+			return fmt.Errorf("custom property %s is set by the hugepages of the VM", name)
+		}
+	}
+	return nil
+}
+
+// customProperties returns the VM custom properties implementing the spec, followed by
+// the custom properties of the spec sorted by name
+func customProperties(spec *ovirtconfigv1.OvirtMachineProviderSpec) []*ovirtsdk.CustomProperty {
+	var properties []*ovirtsdk.CustomProperty
+	if spec.GPU != nil && spec.GPU.MdevType != "" {
+		properties = append(properties, customProperty(mdevTypeProperty, mdevTypeValue(spec.GPU)))
+	}
+	if spec.Hugepages > 0 {
+		properties = append(properties, customProperty(hugepagesProperty, strconv.Itoa(int(spec.Hugepages))))
+	}
+	names := make([]string, 0, len(spec.CustomProperties))
+	for name := range spec.CustomProperties {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		properties = append(properties, customProperty(name, spec.CustomProperties[name]))
+	}
+	return properties
+}
+
+func customProperty(name, value string) *ovirtsdk.CustomProperty {
+	return ovirtsdk.NewCustomPropertyBuilder().Name(name).Value(value).MustBuild()
+}
+
+// mdevTypeValue returns the mdev_type value, the mdev type is repeated per device
+// e.g "nodisplay,nvidia-22,nvidia-22" for two devices without a display
+func mdevTypeValue(gpu *ovirtconfigv1.GPU) string {
+	count := gpu.Count
+	if count < 1 {
+		count = 1
+	}
+	var values []string
+	if gpu.NoDisplay {
+		values = append(values, mdevNoDisplay)
+	}
+	for i := 0; i < count; i++ {
+		values = append(values, gpu.MdevType)
+	}
+	return strings.Join(values, ",")
+}
@@ -0,0 +1,53 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"github.com/pkg/errors"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidateDefaults checks that the engine objects referenced by the defaults exist.
+// A missing object is reported by an error IsInvalidSpec holds for, any other error
+// means the engine couldn't be checked.
+func ValidateDefaults(connection *ovirtsdk.Connection, spec *ovirtconfigv1.OvirtMachineProviderDefaultsSpec) error {
+	is := &InstanceService{Connection: connection}
+	if spec.ClusterId != "" {
+		_, err := connection.SystemService().ClustersService().ClusterService(spec.ClusterId).Get().Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed fetching cluster %s", spec.ClusterId)
+		}
+		if spec.TemplateName != "" {
+			if _, err := is.getTemplate(spec.TemplateName, "", spec.ClusterId); err != nil {
+				return err
+			}
+		}
+	}
+	if spec.StorageDomainId != "" {
+		if _, err := is.getStorageDomain(spec.StorageDomainId); err != nil {
+			return err
+		}
+	}
+	for _, nic := range spec.NetworkInterfaces {
+		if nic.VNICProfileID == "" {
+			// the profile names are resolved in the cluster of the machine
+			if spec.ClusterId != "" {
+				if _, err := is.vnicProfileID(nic, spec.ClusterId); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		_, err := connection.SystemService().VnicProfilesService().ProfileService(nic.VNICProfileID).Get().Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed fetching vNIC profile %s", nic.VNICProfileID)
+		}
+	}
+	return nil
+}
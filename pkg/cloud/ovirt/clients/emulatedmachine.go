@@ -0,0 +1,112 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// emulatedMachineFamilies are the emulated machine types of each architecture, an
+// emulated machine is either one of them or one of their versions, e.g "pc-q35-rhel8.4.0"
+var emulatedMachineFamilies = map[ovirtsdk.Architecture][]string{
+	ovirtsdk.ARCHITECTURE_X86_64: {"pc-i440fx", "pc-q35", "pc", "q35"},
+	ovirtsdk.ARCHITECTURE_PPC64:  {"pseries"},
+	ovirtsdk.ARCHITECTURE_S390X:  {"s390-ccw-virtio"},
+}
+
+var compatibilityVersionPattern = regexp.MustCompile(`^(\d+)\.(\d+)$`)
+
+// ValidateCompatibilityVersion checks the format of the custom compatibility version of the spec
+func ValidateCompatibilityVersion(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.CustomCompatibilityVersion == "" {
+		return nil
+	}
+	_, _, err := parseCompatibilityVersion(spec.CustomCompatibilityVersion)
+	return err
+}
+
+// parseCompatibilityVersion returns the major and minor numbers of a "major.minor" version
+func parseCompatibilityVersion(version string) (int64, int64, error) {
+	match := compatibilityVersionPattern.FindStringSubmatch(version)
+	if match == nil {
+		return 0, 0, fmt.Errorf("invalid compatibility version %q, expected major.minor, e.g 4.6", version)
+	}
+	major, _ := strconv.ParseInt(match[1], 10, 64)
+	minor, _ := strconv.ParseInt(match[2], 10, 64)
+	return major, minor, nil
+}
+
+// ValidateEmulatedMachine checks that the custom compatibility version of the spec is
+// supported by the engine, and that the emulated machine fits the architecture of its
+// cluster and the chipset of its BIOS type
+func (is *InstanceService) ValidateEmulatedMachine(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.CustomCompatibilityVersion != "" {
+		_, err := is.Connection.SystemService().ClusterLevelsService().
+			LevelService(spec.CustomCompatibilityVersion).Get().Send()
+		if err != nil {
+			return errors.Wrapf(err, "compatibility version %s isn't supported by the engine", spec.CustomCompatibilityVersion)
+		}
+	}
+	if spec.EmulatedMachine == "" {
+		return nil
+	}
+	res, err := is.Connection.SystemService().ClustersService().ClusterService(spec.ClusterId).Get().Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed fetching cluster %s", spec.ClusterId)
+	}
+	architecture := ovirtsdk.ARCHITECTURE_UNDEFINED
+	if cpu, ok := res.MustCluster().Cpu(); ok {
+		if a, ok := cpu.Architecture(); ok {
+			architecture = a
+		}
+	}
+	families, ok := emulatedMachineFamilies[architecture]
+	if !ok {
+		// the engine checks the emulated machines of the architectures not known here
+		return nil
+	}
+	family := emulatedMachineFamily(spec.EmulatedMachine, families)
+	if family == "" {
+		return invalidSpecf("emulated machine %s isn't supported by the %s architecture of cluster %s, expected one of %s",
+			spec.EmulatedMachine, architecture, spec.ClusterId, strings.Join(families, ", "))
+	}
+
+	q35 := family == "pc-q35" || family == "q35"
+	i440fx := family == "pc-i440fx" || family == "pc"
+	switch ovirtsdk.BiosType(spec.BiosType) {
+	case ovirtsdk.BIOSTYPE_I440FX_SEA_BIOS:
+		if !i440fx {
+			return invalidSpecf("emulated machine %s doesn't have the i440fx chipset of BIOS type %s",
+				spec.EmulatedMachine, spec.BiosType)
+		}
+	case ovirtsdk.BIOSTYPE_Q35_SEA_BIOS, ovirtsdk.BIOSTYPE_Q35_OVMF, ovirtsdk.BIOSTYPE_Q35_SECURE_BOOT:
+		if !q35 {
+			return invalidSpecf("emulated machine %s doesn't have the q35 chipset of BIOS type %s",
+				spec.EmulatedMachine, spec.BiosType)
+		}
+	}
+	return nil
+}
+
+// emulatedMachineFamily returns the family of the emulated machine, or an empty string
+// if it isn't one of the families
+func emulatedMachineFamily(machine string, families []string) string {
+	for _, family := range families {
+		if machine == family || strings.HasPrefix(machine, family+"-") {
+			return family
+		}
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+)
+
+const (
+	schedulingFailureMarker = "There is no host that satisfies current scheduling constraints"
+	schedulingDetailsMarker = "See below for details:"
+	// certificateErrorMarker prefixes the certificate verification errors of crypto/x509,
+	// the SDK formats the errors it returns so their types are lost
+	certificateErrorMarker = "x509: "
+)
+
+// SchedulingFailureDetails extracts the scheduling filters explanation out of an
+// engine fault raised when no host can run the VM. The second return value is false
+// when err is not a scheduling failure.
+func SchedulingFailureDetails(err error) (string, bool) {
+	if err == nil {
+		return "", false
+	}
+	msg := err.Error()
+	if !strings.Contains(msg, schedulingFailureMarker) {
+		return "", false
+	}
+	i := strings.Index(msg, schedulingDetailsMarker)
+	if i < 0 {
+		return schedulingFailureMarker, true
+	}
+	details := msg[i+len(schedulingDetailsMarker):]
+	// the engine returns the messages as a list, e.g "[Cannot run VM..., The host..]"
+	if end := strings.Index(details, "]"); end >= 0 {
+		details = details[:end]
+	}
+	details = strings.TrimSpace(strings.TrimLeft(details, ", "))
+	if details == "" {
+		return schedulingFailureMarker, true
+	}
+	return details, true
+}
+
+// InProgressError is returned for an engine operation which was started and
+// isn't done yet. It is not a failure, the caller should check on it later
+// instead of waiting for it.
+type InProgressError struct {
+	Operation string
+}
+
+func (e *InProgressError) Error() string {
+	return e.Operation + " is in progress"
+}
+
+// IsInProgress returns true if err is, or wraps, an InProgressError
+func IsInProgress(err error) bool {
+	var inProgress *InProgressError
+	return errors.As(err, &inProgress)
+}
+
+// InvalidSpecError is returned when the provider spec doesn't match the engine, e.g it
+// references an entity which doesn't exist or which doesn't support the requested
+// options. Retrying doesn't help until the spec or the engine is changed.
+type InvalidSpecError struct {
+	msg string
+}
+
+func (e *InvalidSpecError) Error() string {
+	return e.msg
+}
+
+// invalidSpecf returns an InvalidSpecError formatted as fmt.Errorf does
+func invalidSpecf(format string, args ...interface{}) error {
+	return &InvalidSpecError{msg: fmt.Sprintf(format, args...)}
+}
+
+// IsInvalidSpec returns true if err is, or wraps, an InvalidSpecError or the engine
+// fault of an entity which wasn't found, e.g the cluster of the spec was fetched by
+// an ID which doesn't exist. Any other error, e.g the engine is unreachable, is transient.
+func IsInvalidSpec(err error) bool {
+	var invalid *InvalidSpecError
+	var notFound *ovirtsdk.NotFoundError
+	return errors.As(err, &invalid) || errors.As(err, &notFound)
+}
+
+// OwnershipConflictError is returned when the VM of a machine is owned by another
+// machine, e.g a VM left over by a deleted machine of the same name. The provider
+// neither manages nor replaces such a VM.
+type OwnershipConflictError struct {
+	msg string
+}
+
+func (e *OwnershipConflictError) Error() string {
+	return e.msg
+}
+
+// IsOwnershipConflict returns true if err is, or wraps, an OwnershipConflictError
+func IsOwnershipConflict(err error) bool {
+	var conflict *OwnershipConflictError
+	return errors.As(err, &conflict)
+}
+
+// IsCertificateError returns true if err is a failure verifying the engine certificate
+func IsCertificateError(err error) bool {
+	if err == nil {
+		return false
+	}
+	var unknownAuthority x509.UnknownAuthorityError
+	var invalid x509.CertificateInvalidError
+	var hostname x509.HostnameError
+	if errors.As(err, &unknownAuthority) || errors.As(err, &invalid) || errors.As(err, &hostname) {
+		return true
+	}
+	return strings.Contains(err.Error(), certificateErrorMarker)
+}
@@ -0,0 +1,105 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"testing"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/pkg/errors"
+)
+
+func TestSchedulingFailureDetails(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		err         error
+		wantDetails string
+		wantOK      bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "other fault",
+			err:  fmt.Errorf("Fault reason is \"Operation Failed\". Fault detail is \"[Cannot run VM. VM is locked.]\"."),
+		},
+		{
+			name: "scheduling failure with details",
+			err: fmt.Errorf("Fault reason is \"Operation Failed\". Fault detail is \"[Cannot run VM. " +
+				"There is no host that satisfies current scheduling constraints. See below for details:, " +
+				"The host host1 did not satisfy internal filter Memory because its available memory is too low.]\"."),
+			wantDetails: "The host host1 did not satisfy internal filter Memory because its available memory is too low.",
+			wantOK:      true,
+		},
+		{
+			name:        "scheduling failure without details",
+			err:         fmt.Errorf("Cannot run VM. There is no host that satisfies current scheduling constraints."),
+			wantDetails: schedulingFailureMarker,
+			wantOK:      true,
+		},
+		{
+			name:        "scheduling failure with empty details",
+			err:         fmt.Errorf("[There is no host that satisfies current scheduling constraints. See below for details:, ]"),
+			wantDetails: schedulingFailureMarker,
+			wantOK:      true,
+		},
+		{
+			name: "wrapped scheduling failure",
+			err: fmt.Errorf("failed starting VM: %w", fmt.Errorf("[There is no host that satisfies current "+
+				"scheduling constraints. See below for details:, The host h2 did not satisfy filter CPU.]")),
+			wantDetails: "The host h2 did not satisfy filter CPU.",
+			wantOK:      true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			details, ok := SchedulingFailureDetails(tc.err)
+			if ok != tc.wantOK || details != tc.wantDetails {
+				t.Errorf("SchedulingFailureDetails() = %q, %v, want %q, %v", details, ok, tc.wantDetails, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestIsInvalidSpec(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{
+			name: "nil",
+		},
+		{
+			name: "invalid spec",
+			err:  invalidSpecf("template %s was not found", "rhcos"),
+			want: true,
+		},
+		{
+			name: "wrapped invalid spec",
+			err:  errors.Wrapf(invalidSpecf("storage domain %s was not found", "data"), "template disk %s", "disk0"),
+			want: true,
+		},
+		{
+			name: "engine entity not found",
+			err:  errors.Wrapf(&ovirtsdk.NotFoundError{}, "failed fetching cluster %s", "c1"),
+			want: true,
+		},
+		{
+			name: "engine unreachable",
+			err:  errors.Wrapf(fmt.Errorf("dial tcp: connection refused"), "failed fetching cluster %s", "c1"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := IsInvalidSpec(tc.err); got != tc.want {
+				t.Errorf("IsInvalidSpec(%v) = %v, want %v", tc.err, got, tc.want)
+			}
+		})
+	}
+}
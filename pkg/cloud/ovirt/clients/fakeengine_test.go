@@ -0,0 +1,170 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// fakeEngine is an oVirt engine API stub serving the SSO login and the VMs of vms,
+// it counts the engine calls so the benchmarks can report the calls per operation
+type fakeEngine struct {
+	*httptest.Server
+
+	mu sync.Mutex
+	// vms maps the VM IDs to their names
+	vms   map[string]string
+	calls int
+	// logouts counts the closed connections
+	logouts int
+	// failAPI fails the calls to the API root, i.e the connection tests
+	failAPI bool
+}
+
+func newFakeEngine(tb testing.TB) *fakeEngine {
+	e := &fakeEngine{vms: make(map[string]string)}
+	e.Server = httptest.NewServer(http.HandlerFunc(e.serve))
+	tb.Cleanup(e.Close)
+	return e
+}
+
+// apiURL returns the URL of the engine API, as set in the credentials secrets
+func (e *fakeEngine) apiURL() string {
+	return e.URL + engineAPIPath
+}
+
+func (e *fakeEngine) addVM(id, name string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.vms[id] = name
+}
+
+// logoutCount returns the number of connections closed so far
+func (e *fakeEngine) logoutCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.logouts
+}
+
+func (e *fakeEngine) setFailAPI(fail bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.failAPI = fail
+}
+
+// callCount returns the number of engine calls served so far
+func (e *fakeEngine) callCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func (e *fakeEngine) serve(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.calls++
+
+	switch {
+	case r.URL.Path == "/ovirt-engine/sso/oauth/token":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"fake-token"}`)
+	case r.URL.Path == "/ovirt-engine/services/sso-logout":
+		e.logouts++
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	case r.URL.Path == engineAPIPath && e.failAPI:
+		writeXML(w, http.StatusInternalServerError, `<fault><reason>Operation Failed</reason><detail>Engine unavailable</detail></fault>`)
+	case r.URL.Path == engineAPIPath:
+		writeXML(w, http.StatusOK, `<api><product_info><name>oVirt Engine</name></product_info></api>`)
+	case r.URL.Path == engineAPIPath+"/vms":
+		name := strings.Trim(strings.TrimPrefix(r.URL.Query().Get("search"), "name="), `"`)
+		var body strings.Builder
+		body.WriteString("<vms>")
+		for id, vmName := range e.vms {
+			if vmName == name {
+				body.WriteString(vmXML(id, vmName))
+			}
+		}
+		body.WriteString("</vms>")
+		writeXML(w, http.StatusOK, body.String())
+	case strings.HasPrefix(r.URL.Path, engineAPIPath+"/vms/"):
+		id := strings.TrimPrefix(r.URL.Path, engineAPIPath+"/vms/")
+		name, ok := e.vms[id]
+		if !ok {
+			writeXML(w, http.StatusNotFound, `<fault><reason>Operation Failed</reason><detail>Entity not found: `+id+`</detail></fault>`)
+			return
+		}
+		writeXML(w, http.StatusOK, vmXML(id, name))
+	default:
+		writeXML(w, http.StatusNotFound, `<fault><reason>Operation Failed</reason><detail>Not found: `+r.URL.Path+`</detail></fault>`)
+	}
+}
+
+func vmXML(id, name string) string {
+	return fmt.Sprintf(`<vm href="%s/vms/%s" id="%s"><name>%s</name><status>up</status></vm>`,
+		engineAPIPath, id, id, name)
+}
+
+func writeXML(w http.ResponseWriter, status int, body string) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+body)
+}
+
+// countingClient counts the API server reads of the wrapped client
+type countingClient struct {
+	client.Client
+
+	mu    sync.Mutex
+	reads int
+}
+
+func (c *countingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	c.mu.Lock()
+	c.reads++
+	c.mu.Unlock()
+	return c.Client.Get(ctx, key, obj)
+}
+
+func (c *countingClient) readCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.reads
+}
+
+// credentialsSecret returns the credentials secret of the fake engine
+func credentialsSecret(e *fakeEngine, namespace, name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Data: map[string][]byte{
+			"ovirt_url":      []byte(e.apiURL()),
+			"ovirt_username": []byte("admin@internal"),
+			"ovirt_password": []byte("secret"),
+			"ovirt_insecure": []byte("true"),
+		},
+	}
+}
+
+// newFakeClient returns a fake API server client holding the objects
+func newFakeClient(objs ...runtime.Object) *countingClient {
+	return &countingClient{Client: fake.NewClientBuilder().WithScheme(scheme.Scheme).WithRuntimeObjects(objs...).Build()}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidateNetworkConfiguration checks the static addresses of the network configuration of the spec
+func ValidateNetworkConfiguration(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	config := spec.NetworkConfiguration
+	if config == nil {
+		return nil
+	}
+	if len(config.Nics) == 0 {
+		return fmt.Errorf("the network configuration has no NIC")
+	}
+	names := make(map[string]bool, len(config.Nics))
+	for _, nic := range config.Nics {
+		if nic.Name == "" {
+			return fmt.Errorf("a NIC configuration has no name")
+		}
+		if names[nic.Name] {
+			return fmt.Errorf("NIC %s is configured more than once", nic.Name)
+		}
+		names[nic.Name] = true
+
+		ip := net.ParseIP(nic.IP)
+		if ip == nil {
+			return fmt.Errorf("NIC %s has an invalid IP address %q", nic.Name, nic.IP)
+		}
+		if ip.To4() != nil {
+			if mask := net.ParseIP(nic.Netmask); mask == nil || mask.To4() == nil {
+				return fmt.Errorf("NIC %s has an invalid IPv4 netmask %q", nic.Name, nic.Netmask)
+			}
+		} else if prefix, err := strconv.Atoi(nic.Netmask); err != nil || prefix < 0 || prefix > 128 {
+			return fmt.Errorf("NIC %s has an invalid IPv6 prefix length %q", nic.Name, nic.Netmask)
+		}
+		if nic.Gateway != "" {
+			gateway := net.ParseIP(nic.Gateway)
+			if gateway == nil || (gateway.To4() == nil) != (ip.To4() == nil) {
+				return fmt.Errorf("NIC %s has an invalid gateway %q", nic.Name, nic.Gateway)
+			}
+		}
+	}
+	for _, server := range config.DNSServers {
+		if net.ParseIP(server) == nil {
+			return fmt.Errorf("invalid DNS server address %q", server)
+		}
+	}
+	return nil
+}
+
+// setNetworkConfiguration sets the static network configuration on the VM initialization,
+// the engine renders it into the cloud-init network configuration of the guest
+func setNetworkConfiguration(init *ovirtsdk.InitializationBuilder, config *ovirtconfigv1.NetworkConfiguration) {
+	nics := make([]*ovirtsdk.NicConfiguration, 0, len(config.Nics))
+	for _, nic := range config.Nics {
+		ip := ovirtsdk.NewIpBuilder().Address(nic.IP).Netmask(nic.Netmask)
+		if nic.Gateway != "" {
+			ip.Gateway(nic.Gateway)
+		}
+		builder := ovirtsdk.NewNicConfigurationBuilder().Name(nic.Name).OnBoot(true)
+		if net.ParseIP(nic.IP).To4() != nil {
+			ip.Version(ovirtsdk.IPVERSION_V4)
+			builder.BootProtocol(ovirtsdk.BOOTPROTOCOL_STATIC).IpBuilder(ip)
+		} else {
+			ip.Version(ovirtsdk.IPVERSION_V6)
+			builder.Ipv6BootProtocol(ovirtsdk.BOOTPROTOCOL_STATIC).Ipv6Builder(ip)
+		}
+		nics = append(nics, builder.MustBuild())
+	}
+	init.NicConfigurationsOfAny(nics...)
+	if len(config.DNSServers) > 0 {
+		init.DnsServers(strings.Join(config.DNSServers, " "))
+	}
+	if len(config.DNSSearch) > 0 {
+		init.DnsSearch(strings.Join(config.DNSSearch, " "))
+	}
+}
@@ -0,0 +1,59 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"github.com/pkg/errors"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// instanceTypeID returns the ID of the instance type of the spec, looked up by name
+// when the spec has no instance type ID, or an empty string if the spec has neither.
+// The instance types are global to the engine, their names are unique.
+func (is *InstanceService) instanceTypeID(spec *ovirtconfigv1.OvirtMachineProviderSpec) (string, error) {
+	if spec.InstanceTypeId != "" || spec.InstanceTypeName == "" {
+		return spec.InstanceTypeId, nil
+	}
+	res, err := is.Connection.SystemService().InstanceTypesService().
+		List().Search("name=" + searchValue(spec.InstanceTypeName)).Send()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed searching instance type %s", spec.InstanceTypeName)
+	}
+	for _, instanceType := range res.MustInstanceType().Slice() {
+		if name, ok := instanceType.Name(); ok && name == spec.InstanceTypeName {
+			return instanceType.MustId(), nil
+		}
+	}
+	return "", invalidSpecf("instance type %s was not found", spec.InstanceTypeName)
+}
+
+// InstanceTypeResources returns the CPUs and the memory in MiBs of the instance type of
+// the spec as defined in the engine, or zeros if the spec has no instance type.
+func (is *InstanceService) InstanceTypeResources(spec *ovirtconfigv1.OvirtMachineProviderSpec) (cpus int32, memoryMB int64, err error) {
+	id, err := is.instanceTypeID(spec)
+	if err != nil || id == "" {
+		return 0, 0, err
+	}
+	res, err := is.Connection.SystemService().InstanceTypesService().
+		InstanceTypeService(id).Get().Send()
+	if err != nil {
+		return 0, 0, errors.Wrapf(err, "failed fetching instance type %s", id)
+	}
+	instanceType := res.MustInstanceType()
+	if cpu, ok := instanceType.Cpu(); ok {
+		if topology, ok := cpu.Topology(); ok {
+			sockets, _ := topology.Sockets()
+			cores, _ := topology.Cores()
+			threads, _ := topology.Threads()
+			cpus = int32(sockets * cores * threads)
+		}
+	}
+	if memory, ok := instanceType.Memory(); ok {
+		memoryMB = memory / (1 << 20)
+	}
+	return cpus, memoryMB, nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/pkg/errors"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// InventoryEntry is a machine of a cluster side by side with its VM. The machine
+// fields of a VM without a machine are empty, and the VM fields of a machine without a VM.
+type InventoryEntry struct {
+	MachineNamespace string
+	MachineName      string
+	MachinePhase     string
+	NodeName         string
+
+	VMName   string
+	VMID     string
+	VMStatus string
+	Host     string
+	IPs      []string
+}
+
+// ClusterInventory lists the machines of the OpenShift cluster with the ID next to the
+// VMs tagged with the cluster ID. A VM is matched with the machine recorded as its owner,
+// or with the machine of its name for the VMs created before the owner was recorded.
+// The entries are ordered by machine name, the VMs without a machine come last.
+func ClusterInventory(ctx context.Context, c client.Client, connection *ovirtsdk.Connection, clusterID string) ([]InventoryEntry, error) {
+	if clusterID == "" {
+		return nil, fmt.Errorf("the cluster ID must be set")
+	}
+	machines := &machinev1.MachineList{}
+	if err := c.List(ctx, machines, client.MatchingLabels{clusterIDLabel: clusterID}); err != nil {
+		return nil, fmt.Errorf("failed listing the machines of cluster %s: %v", clusterID, err)
+	}
+	res, err := connection.SystemService().VmsService().List().
+		Search("tag=" + searchValue(clusterID)).Follow("host").Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed listing the VMs of cluster %s", clusterID)
+	}
+	vms := res.MustVms().Slice()
+
+	is := &InstanceService{Connection: connection}
+	matched := make(map[string]bool, len(vms))
+	entries := make([]InventoryEntry, 0, len(machines.Items)+len(vms))
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		entry := InventoryEntry{
+			MachineNamespace: machine.Namespace,
+			MachineName:      machine.Name,
+		}
+		if machine.Status.Phase != nil {
+			entry.MachinePhase = *machine.Status.Phase
+		}
+		if machine.Status.NodeRef != nil {
+			entry.NodeName = machine.Status.NodeRef.Name
+		}
+		if vm := machineVM(machine, vms, matched); vm != nil {
+			is.fillInventoryVM(&entry, vm)
+		}
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].MachineName < entries[j].MachineName
+	})
+	for _, vm := range vms {
+		if matched[vm.MustId()] {
+			continue
+		}
+		entry := InventoryEntry{}
+		is.fillInventoryVM(&entry, vm)
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// machineVM returns the VM of the machine among the VMs which aren't matched yet
+func machineVM(machine *machinev1.Machine, vms []*ovirtsdk.Vm, matched map[string]bool) *ovirtsdk.Vm {
+	var byName *ovirtsdk.Vm
+	for _, vm := range vms {
+		if matched[vm.MustId()] {
+			continue
+		}
+		uid := MachineUID(vm)
+		if uid == machine.UID {
+			matched[vm.MustId()] = true
+			return vm
+		}
+		if uid == "" && vm.MustName() == machine.Name {
+			byName = vm
+		}
+	}
+	if byName != nil {
+		matched[byName.MustId()] = true
+	}
+	return byName
+}
+
+func (is *InstanceService) fillInventoryVM(entry *InventoryEntry, vm *ovirtsdk.Vm) {
+	entry.VMName = vm.MustName()
+	entry.VMID = vm.MustId()
+	entry.VMStatus = string(vm.MustStatus())
+	if host, ok := vm.Host(); ok {
+		entry.Host, _ = host.Name()
+	}
+	if vm.MustStatus() == ovirtsdk.VMSTATUS_UP {
+		// the addresses are reported by the guest agent, a VM without them is listed anyway
+		entry.IPs, _ = is.FindVirtualMachineIPs(entry.VMID, nil, "")
+	}
+}
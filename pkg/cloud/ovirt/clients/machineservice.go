@@ -9,20 +9,31 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"net"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/pkg/errors"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/klog"
 
 	ovirtsdk "github.com/ovirt/go-ovirt"
 
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
-	"github.com/openshift/machine-api-operator/pkg/util"
 
 	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+)
+
+const (
+	// DefaultCloneTimeout is the time to wait for the template disks to be cloned
+	DefaultCloneTimeout = 60 * time.Minute
+	// DefaultDiskExtensionTimeout is the time to wait for the OS disk to be extended
+	DefaultDiskExtensionTimeout = 20 * time.Minute
 )
 
 type InstanceService struct {
@@ -30,6 +41,44 @@ type InstanceService struct {
 	ClusterId    string
 	TemplateName string
 	MachineName  string
+	// MachineUID is the UID of the machine, recorded on the VM to verify its owner.
+	MachineUID types.UID
+
+	// OnCreatePhase is called after each completed phase of the VM creation.
+	OnCreatePhase func(phase ovirtconfigv1.CreatePhase)
+	// OnDeleteProgress is called when the VM deletion moves to its next step.
+	OnDeleteProgress func(reason, message string)
+	// DiskExtensionTimeout is the time to wait for the OS disk to be extended,
+	// DefaultDiskExtensionTimeout if 0.
+	DiskExtensionTimeout time.Duration
+
+	scope          *clusterScope
+	scopeClusterID string
+}
+
+// createPhases lists the VM creation phases in the order they are performed
+var createPhases = []ovirtconfigv1.CreatePhase{
+	ovirtconfigv1.CreatePhaseVMCreated,
+	ovirtconfigv1.CreatePhaseDisksReady,
+	ovirtconfigv1.CreatePhaseNICsConfigured,
+	ovirtconfigv1.CreatePhaseTagged,
+	ovirtconfigv1.CreatePhaseAffinityGroupsApplied,
+	ovirtconfigv1.CreatePhaseConsoleConfigured,
+	ovirtconfigv1.CreatePhaseNUMAConfigured,
+	ovirtconfigv1.CreatePhaseVMStarted,
+}
+
+// CreatePhaseReached returns true if the phase is completed when the creation is at the current phase
+func CreatePhaseReached(current, phase ovirtconfigv1.CreatePhase) bool {
+	for _, p := range createPhases {
+		if p == phase {
+			return true
+		}
+		if p == current {
+			return false
+		}
+	}
+	return false
 }
 
 type Instance struct {
@@ -63,6 +112,7 @@ func NewInstanceServiceFromMachine(machine *machinev1.Machine, connection *ovirt
 	service.ClusterId = machineSpec.ClusterId
 	service.TemplateName = machineSpec.TemplateName
 	service.MachineName = machine.Name
+	service.MachineUID = machine.UID
 	return service, err
 }
 
@@ -82,43 +132,212 @@ func (is *InstanceService) InstanceCreate(
 		return nil, fmt.Errorf("failed to fetch user data secret for the machine namespace: %s", err)
 	}
 
+	// the user data is the ignition config, the cloud-init content, or the sysprep answer
+	// file of a Windows guest, the engine passes it to the guest as is
 	ignition, ok := userDataSecret.Data["userData"]
 	if !ok {
 		return nil, fmt.Errorf("failed extracting ignition from user data secret %v", string(ignition))
 	}
 	cluster := ovirtsdk.NewClusterBuilder().Id(providerSpec.ClusterId).MustBuild()
 	template := ovirtsdk.NewTemplateBuilder().Name(providerSpec.TemplateName).MustBuild()
-	init := ovirtsdk.NewInitializationBuilder().
+	if providerSpec.TemplateID != "" {
+		template = ovirtsdk.NewTemplateBuilder().Id(providerSpec.TemplateID).MustBuild()
+	} else if providerSpec.TemplateVersion != "" {
+		// the sub versions share the name of the base version, they are selected by ID
+		versioned, err := is.getTemplate(providerSpec.TemplateName, providerSpec.TemplateVersion, providerSpec.ClusterId)
+		if err != nil {
+			return nil, err
+		}
+		klog.Infof("Creating VM %s from version %s of template %s, template ID %s",
+			machine.Name, providerSpec.TemplateVersion, providerSpec.TemplateName, versioned.MustId())
+		template = ovirtsdk.NewTemplateBuilder().Id(versioned.MustId()).MustBuild()
+	}
+	initBuilder := ovirtsdk.NewInitializationBuilder().
 		CustomScript(string(ignition)).
-		HostName(machine.Name).
-		MustBuild()
+		HostName(machine.Name)
+	if providerSpec.NetworkConfiguration != nil {
+		setNetworkConfiguration(initBuilder, providerSpec.NetworkConfiguration)
+	}
+	init := initBuilder.MustBuild()
 
 	vmBuilder := ovirtsdk.NewVmBuilder().
 		Name(machine.Name).
+		Comment(ownerComment(expandMachineText(providerSpec.Comment, machine), machine.UID)).
 		Cluster(cluster).
 		Template(template).
 		Initialization(init)
 
+	if providerSpec.Description != "" {
+		vmBuilder.Description(expandMachineText(providerSpec.Description, machine))
+	}
 	if providerSpec.VMType != "" {
 		vmBuilder.Type(ovirtsdk.VmType(providerSpec.VMType))
 	}
-	if providerSpec.InstanceTypeId != "" {
+	var cpuBuilder *ovirtsdk.CpuBuilder
+	instanceTypeID, err := is.instanceTypeID(providerSpec)
+	if err != nil {
+		return nil, err
+	}
+	if instanceTypeID != "" {
 		vmBuilder.InstanceTypeBuilder(
 			ovirtsdk.NewInstanceTypeBuilder().
-				Id(providerSpec.InstanceTypeId))
+				Id(instanceTypeID))
 	} else {
 		if providerSpec.CPU != nil {
-			vmBuilder.CpuBuilder(
-				ovirtsdk.NewCpuBuilder().
-					TopologyBuilder(ovirtsdk.NewCpuTopologyBuilder().
-						Cores(int64(providerSpec.CPU.Cores)).
-						Sockets(int64(providerSpec.CPU.Sockets)).
-						Threads(int64(providerSpec.CPU.Threads))))
+			cpuBuilder = ovirtsdk.NewCpuBuilder().
+				TopologyBuilder(ovirtsdk.NewCpuTopologyBuilder().
+					Cores(int64(providerSpec.CPU.Cores)).
+					Sockets(int64(providerSpec.CPU.Sockets)).
+					Threads(int64(providerSpec.CPU.Threads)))
 		}
 		if providerSpec.MemoryMB > 0 {
 			vmBuilder.Memory(int64(math.Pow(2, 20)) * int64(providerSpec.MemoryMB))
 		}
 	}
+	if providerSpec.GuaranteedMemoryMB > 0 || providerSpec.MaxMemoryMB > 0 || providerSpec.Ballooning != nil {
+		memoryPolicy := ovirtsdk.NewMemoryPolicyBuilder()
+		if providerSpec.GuaranteedMemoryMB > 0 {
+			memoryPolicy.Guaranteed(int64(math.Pow(2, 20)) * int64(providerSpec.GuaranteedMemoryMB))
+		}
+		if providerSpec.MaxMemoryMB > 0 {
+			memoryPolicy.Max(int64(math.Pow(2, 20)) * int64(providerSpec.MaxMemoryMB))
+		}
+		if providerSpec.Ballooning != nil {
+			memoryPolicy.Ballooning(*providerSpec.Ballooning)
+		}
+		vmBuilder.MemoryPolicyBuilder(memoryPolicy)
+	}
+	if len(providerSpec.CPUPinning) > 0 {
+		if cpuBuilder == nil {
+			cpuBuilder = ovirtsdk.NewCpuBuilder()
+		}
+		cpuBuilder.CpuTuneBuilder(ovirtsdk.NewCpuTuneBuilder().
+			VcpuPinsOfAny(vcpuPins(providerSpec.CPUPinning)...))
+	}
+	if providerSpec.CPUShares > 0 {
+		vmBuilder.CpuShares(int64(providerSpec.CPUShares))
+	}
+	if providerSpec.Stateless {
+		vmBuilder.Stateless(true)
+	}
+	if providerSpec.DeleteProtected {
+		vmBuilder.DeleteProtected(true)
+	}
+	switch providerSpec.CPUType {
+	case "":
+	case ovirtconfigv1.CPUTypeHostPassthrough, ovirtconfigv1.CPUTypeHostModel:
+		if cpuBuilder == nil {
+			cpuBuilder = ovirtsdk.NewCpuBuilder()
+		}
+		cpuBuilder.Mode(ovirtsdk.CpuMode(providerSpec.CPUType))
+	default:
+		vmBuilder.CustomCpuModel(providerSpec.CPUType)
+	}
+	if cpuBuilder != nil {
+		vmBuilder.CpuBuilder(cpuBuilder)
+	}
+
+	if providerSpec.IOThreads > 0 {
+		vmBuilder.IoBuilder(ovirtsdk.NewIoBuilder().Threads(int64(providerSpec.IOThreads)))
+	}
+	if providerSpec.MultiQueuesEnabled != nil {
+		vmBuilder.MultiQueuesEnabled(*providerSpec.MultiQueuesEnabled)
+	}
+
+	if providerSpec.SerialNumber != nil {
+		serial := ovirtsdk.NewSerialNumberBuilder().Policy(ovirtsdk.SerialNumberPolicy(providerSpec.SerialNumber.Policy))
+		if providerSpec.SerialNumber.Value != "" {
+			serial.Value(expandMachineText(providerSpec.SerialNumber.Value, machine))
+		}
+		vmBuilder.SerialNumberBuilder(serial)
+	}
+	if providerSpec.RNG != nil {
+		rng := ovirtsdk.NewRngDeviceBuilder().Source(ovirtsdk.RngSource(providerSpec.RNG.Source))
+		if providerSpec.RNG.RateBytes > 0 {
+			rng.RateBuilder(ovirtsdk.NewRateBuilder().
+				Bytes(int64(providerSpec.RNG.RateBytes)).
+				Period(int64(providerSpec.RNG.RatePeriodMs)))
+		}
+		vmBuilder.RngDeviceBuilder(rng)
+	}
+
+	if providerSpec.BiosType != "" {
+		vmBuilder.BiosBuilder(ovirtsdk.NewBiosBuilder().Type(ovirtsdk.BiosType(providerSpec.BiosType)))
+	}
+	if providerSpec.EmulatedMachine != "" {
+		vmBuilder.CustomEmulatedMachine(providerSpec.EmulatedMachine)
+	}
+	if providerSpec.CustomCompatibilityVersion != "" {
+		major, minor, err := parseCompatibilityVersion(providerSpec.CustomCompatibilityVersion)
+		if err != nil {
+			return nil, err
+		}
+		vmBuilder.CustomCompatibilityVersionBuilder(ovirtsdk.NewVersionBuilder().Major(major).Minor(minor))
+	}
+
+	if len(providerSpec.BootDevices) > 0 {
+		devices := make([]ovirtsdk.BootDevice, 0, len(providerSpec.BootDevices))
+		for _, device := range providerSpec.BootDevices {
+			devices = append(devices, ovirtsdk.BootDevice(device))
+		}
+		vmBuilder.OsBuilder(ovirtsdk.NewOperatingSystemBuilder().
+			BootBuilder(ovirtsdk.NewBootBuilder().DevicesOfAny(devices...)))
+	}
+
+	placementPolicy, err := is.placementPolicy(machine, providerSpec)
+	if err != nil {
+		return nil, err
+	}
+	if placementPolicy != nil {
+		vmBuilder.PlacementPolicyBuilder(placementPolicy)
+	}
+
+	if providerSpec.OSDisk != nil && providerSpec.OSDisk.Interface == string(ovirtsdk.DISKINTERFACE_VIRTIO_SCSI) {
+		vmBuilder.VirtioScsiBuilder(ovirtsdk.NewVirtioScsiBuilder().Enabled(true))
+	}
+
+	if properties := customProperties(providerSpec); len(properties) > 0 {
+		vmBuilder.CustomPropertiesOfAny(properties...)
+	}
+
+	if providerSpec.Console != nil && providerSpec.Console.SerialConsole != nil {
+		vmBuilder.ConsoleBuilder(ovirtsdk.NewConsoleBuilder().Enabled(*providerSpec.Console.SerialConsole))
+	}
+
+	if providerSpec.Lease != nil {
+		vmBuilder.LeaseBuilder(
+			ovirtsdk.NewStorageDomainLeaseBuilder().
+				StorageDomainBuilder(ovirtsdk.NewStorageDomainBuilder().
+					Id(providerSpec.Lease.StorageDomainId)))
+	}
+	if providerSpec.HighlyAvailable || providerSpec.Lease != nil || providerSpec.HAPriority > 0 {
+		highAvailability := ovirtsdk.NewHighAvailabilityBuilder().
+			Enabled(providerSpec.HighlyAvailable || providerSpec.Lease != nil)
+		if providerSpec.HAPriority > 0 {
+			highAvailability.Priority(int64(providerSpec.HAPriority))
+		}
+		vmBuilder.HighAvailabilityBuilder(highAvailability)
+	}
+	if providerSpec.ResumeBehavior != "" {
+		vmBuilder.StorageErrorResumeBehaviour(ovirtsdk.VmStorageErrorResumeBehaviour(providerSpec.ResumeBehavior))
+	}
+
+	diskAttachments, err := is.templateDiskAttachments(providerSpec)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed placing the template disks")
+	}
+	if len(diskAttachments) > 0 {
+		vmBuilder.DiskAttachmentsOfAny(diskAttachments...)
+	}
+	// the disks can be placed on a different storage domain than the template's only
+	// when cloning them, thin provisioned disks stay on the template storage domain
+	clone := len(diskAttachments) > 0
+	if providerSpec.Clone != nil {
+		if !*providerSpec.Clone && clone {
+			return nil, fmt.Errorf("the template disks must be cloned to be placed on other storage domains")
+		}
+		clone = *providerSpec.Clone
+	}
 
 	vm, err := vmBuilder.Build()
 	if err != nil {
@@ -126,55 +345,179 @@ func (is *InstanceService) InstanceCreate(
 	}
 
 	klog.Infof("creating VM: %v", vm.MustName())
-	response, err := is.Connection.SystemService().VmsService().Add().Vm(vm).Send()
+	addRequest := is.Connection.SystemService().VmsService().Add().Vm(vm).Clone(clone)
+	if providerSpec.AutoPinningPolicy != "" {
+		addRequest.AutoPinningPolicy(autoPinningPolicy(providerSpec.AutoPinningPolicy))
+	}
+	response, err := addRequest.Send()
 	if err != nil {
-		klog.Errorf("Failed creating VM", err)
+		klog.Errorf("Failed creating VM: %v", err)
 		return nil, err
 	}
 
-	vmID := response.MustVm().MustId()
+	is.reportCreatePhase(ovirtconfigv1.CreatePhaseVMCreated)
+	createdVM := response.MustVm()
+	if createdVM.MustStatus() != ovirtsdk.VMSTATUS_DOWN {
+		createdVM = is.refreshIfCloneDone(createdVM)
+	}
+	if createdVM.MustStatus() != ovirtsdk.VMSTATUS_DOWN {
+		// the template disks are being cloned, the VM is configured once they are done
+		return &Instance{createdVM}, nil
+	}
 
-	ovirtClusterID := machine.Labels["machine.openshift.io/cluster-api-cluster"]
+	err = is.ConfigureInstance(machine, providerSpec, createdVM, ovirtconfigv1.CreatePhaseVMCreated)
+	if err != nil {
+		return nil, err
+	}
+	return &Instance{createdVM}, nil
+}
 
-	err = is.Connection.WaitForVM(vmID, ovirtsdk.VMSTATUS_DOWN, time.Minute)
+// refreshIfCloneDone returns the VM fetched again when the engine job creating it is
+// already over, e.g for a thin template or a fast storage, so the VM is configured right
+// away instead of after the first requeue. Otherwise the given VM is returned.
+func (is *InstanceService) refreshIfCloneDone(vm *ovirtsdk.Vm) *ovirtsdk.Vm {
+	progress, err := is.CloneProgress()
+	if err != nil {
+		klog.V(3).Infof("Failed checking the creation job of VM %s: %v", is.MachineName, err)
+		return vm
+	}
+	if progress >= 0 {
+		return vm
+	}
+	res, err := is.Connection.SystemService().VmsService().VmService(vm.MustId()).Get().Send()
 	if err != nil {
-		return nil, errors.Wrap(err, "timed out waiting for the VM creation to finish")
+		klog.V(3).Infof("Failed fetching the created VM %s: %v", is.MachineName, err)
+		return vm
 	}
+	klog.V(5).Infof("The creation job of VM %s is over, VM status is %s", is.MachineName, res.MustVm().MustStatus())
+	return res.MustVm()
+}
 
-	vmService := is.Connection.SystemService().VmsService().VmService(vmID)
+// ConfigureInstance performs the creation phases which follow the VM creation, skipping
+// the phases up to and including the completed one, so an interrupted creation can be
+// resumed without repeating the steps which were already done.
+func (is *InstanceService) ConfigureInstance(
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	vm *ovirtsdk.Vm,
+	completed ovirtconfigv1.CreatePhase) error {
 
-	if providerSpec.OSDisk != nil {
-		err = is.handleDiskExtension(vmService, response, providerSpec)
-		if err != nil {
-			return nil, err
+	vmService := is.Connection.SystemService().VmsService().VmService(vm.MustId())
+	steps := []struct {
+		phase ovirtconfigv1.CreatePhase
+		run   func() error
+	}{
+		{ovirtconfigv1.CreatePhaseDisksReady, func() error {
+			if providerSpec.OSDisk == nil {
+				return nil
+			}
+			if err := is.handleDiskAttachment(vmService, vm, providerSpec.OSDisk); err != nil {
+				return err
+			}
+			if err := is.handleWipeAfterDelete(vmService, vm, providerSpec); err != nil {
+				return err
+			}
+			return is.handleDiskExtension(vmService, vm, providerSpec)
+		}},
+		{ovirtconfigv1.CreatePhaseNICsConfigured, func() error {
+			return errors.Wrapf(is.handleNics(vmService, providerSpec),
+				"failed handling nics creation for VM %s", vm.MustName())
+		}},
+		{ovirtconfigv1.CreatePhaseTagged, func() error {
+			is.handleTags(vmService, machine)
+			return nil
+		}},
+		{ovirtconfigv1.CreatePhaseAffinityGroupsApplied, func() error {
+			if err := is.handleAffinityGroups(vm, machine, providerSpec); err != nil {
+				return err
+			}
+			return is.handleAffinityLabels(vmService, vm, providerSpec)
+		}},
+		{ovirtconfigv1.CreatePhaseConsoleConfigured, func() error {
+			if err := is.handleGraphicsConsoles(vmService, providerSpec); err != nil {
+				return err
+			}
+			return is.handleConsoleAccess(vmService, providerSpec)
+		}},
+		{ovirtconfigv1.CreatePhaseNUMAConfigured, func() error {
+			return is.handleNUMANodes(vmService, providerSpec.NUMANodes)
+		}},
+	}
+	for _, step := range steps {
+		if CreatePhaseReached(completed, step.phase) {
+			continue
+		}
+		if err := step.run(); err != nil {
+			return err
 		}
+		is.reportCreatePhase(step.phase)
 	}
+	return nil
+}
 
-	err = is.handleNics(vmService, providerSpec)
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed handling nics creation for VM %s", vm.MustName())
+func (is *InstanceService) reportCreatePhase(phase ovirtconfigv1.CreatePhase) {
+	if is.OnCreatePhase != nil {
+		is.OnCreatePhase(phase)
 	}
+}
 
-	_, err = is.Connection.SystemService().VmsService().
-		VmService(response.MustVm().MustId()).
-		TagsService().Add().
+// handleTags tags the VM with the OpenShift cluster ID, unless it is already tagged.
+// Failures are logged and skipped.
+func (is *InstanceService) handleTags(vmService *ovirtsdk.VmService, machine *machinev1.Machine) {
+	ovirtClusterID := machine.Labels[clusterIDLabel]
+	tags, err := vmService.TagsService().List().Send()
+	if err != nil {
+		klog.Errorf("Failed to list the VM tags, skipping: %v", err)
+		return
+	}
+	for _, tag := range tags.MustTags().Slice() {
+		if name, ok := tag.Name(); ok && name == ovirtClusterID {
+			klog.V(5).Infof("VM is already tagged with %s, skipping", ovirtClusterID)
+			return
+		}
+	}
+	_, err = vmService.TagsService().Add().
 		Tag(ovirtsdk.NewTagBuilder().Name(ovirtClusterID).MustBuild()).
 		Send()
 	if err != nil {
-		klog.Errorf("Failed to add tag to VM, skipping", err)
+		klog.Errorf("Failed to add tag to VM, skipping: %v", err)
 	}
+}
 
-	err = is.handleAffinityGroups(
-		response.MustVm(),
-		providerSpec.ClusterId,
-		providerSpec.AffinityGroupsNames)
+// CloneProgress returns the completed percentage of the engine job creating the VM
+// from its template, or -1 if there is no such job running.
+func (is *InstanceService) CloneProgress() (int, error) {
+	jobsService := is.Connection.SystemService().JobsService()
+	res, err := jobsService.List().Search("status=started").Send()
 	if err != nil {
-		return nil, err
+		return -1, err
 	}
-	return &Instance{response.MustVm()}, nil
+	for _, job := range res.MustJobs().Slice() {
+		// i.e "Creating VM worker-0 from Template rhcos in Cluster Default"
+		description, _ := job.Description()
+		if !strings.Contains(description, "VM "+is.MachineName+" ") {
+			continue
+		}
+		steps, err := jobsService.JobService(job.MustId()).StepsService().List().Send()
+		if err != nil {
+			return -1, err
+		}
+		total, count := 0, 0
+		for _, step := range steps.MustSteps().Slice() {
+			if progress, ok := step.Progress(); ok {
+				total += int(progress)
+				count++
+			}
+		}
+		if count == 0 {
+			return 0, nil
+		}
+		return total / count, nil
+	}
+	return -1, nil
 }
 
-func (is *InstanceService) handleDiskExtension(vmService *ovirtsdk.VmService, createdVM *ovirtsdk.VmsServiceAddResponse, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+func (is *InstanceService) handleDiskExtension(vmService *ovirtsdk.VmService, createdVM *ovirtsdk.Vm, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) error {
 	attachmentsResponse, err := vmService.DiskAttachmentsService().List().Send()
 	if err != nil {
 		return err
@@ -189,7 +532,7 @@ func (is *InstanceService) handleDiskExtension(vmService *ovirtsdk.VmService, cr
 	}
 	if bootableDiskAttachment == nil {
 		return fmt.Errorf("the VM %s(%s) doesn't have a bootable disk - was Blank template used by mistake?",
-			createdVM.MustVm().MustName(), createdVM.MustVm().MustId())
+			createdVM.MustName(), createdVM.MustId())
 	}
 	// extend the disk if requested size is bigger than template. We won't support shrinking it.
 	newDiskSize := providerSpec.OSDisk.SizeGB * int64(math.Pow(2, 30))
@@ -202,26 +545,44 @@ func (is *InstanceService) handleDiskExtension(vmService *ovirtsdk.VmService, cr
 
 	size := getDisk.MustDisk().MustProvisionedSize()
 	if newDiskSize < size {
-		klog.Warning("The machine spec specified new disk size %d, and the current disk size is %d. Shrinking is "+
+		klog.Warningf("The machine spec specified new disk size %d, and the current disk size is %d. Shrinking is "+
 			"not supported.", newDiskSize, size)
 	}
 	if newDiskSize > size {
 		klog.Infof("Extending the OS disk from %d to %d", size, newDiskSize)
-		bootableDiskAttachment.SetDisk(getDisk.MustDisk())
-		bootableDiskAttachment.
-			MustDisk().
-			SetProvisionedSize(newDiskSize)
+		if isManagedBlockDisk(getDisk.MustDisk()) {
+			// Managed Block Storage disks reject updates carrying image attributes
+			// like format and sparseness, send only the new size.
+			bootableDiskAttachment = ovirtsdk.NewDiskAttachmentBuilder().
+				Id(bootableDiskAttachment.MustId()).
+				DiskBuilder(ovirtsdk.NewDiskBuilder().
+					Id(getDisk.MustDisk().MustId()).
+					ProvisionedSize(newDiskSize)).
+				MustBuild()
+		} else {
+			bootableDiskAttachment.SetDisk(getDisk.MustDisk())
+			bootableDiskAttachment.
+				MustDisk().
+				SetProvisionedSize(newDiskSize)
+		}
 		_, err := vmService.DiskAttachmentsService().
 			AttachmentService(bootableDiskAttachment.MustId()).
 			Update().
 			DiskAttachment(bootableDiskAttachment).
 			Send()
 		if err != nil {
+			if isManagedBlockDisk(getDisk.MustDisk()) {
+				return fmt.Errorf("failed to extend the OS disk on the Managed Block Storage domain - %s", err)
+			}
 			return fmt.Errorf("failed to update the OS disk - %s", err)
 		}
 		klog.Infof("Waiting while extending the OS disk")
 		// wait for the disk extension to be over
-		err = is.Connection.WaitForDisk(bootableDiskAttachment.MustId(), ovirtsdk.DISKSTATUS_OK, 20*time.Minute)
+		timeout := is.DiskExtensionTimeout
+		if timeout == 0 {
+			timeout = DefaultDiskExtensionTimeout
+		}
+		err = is.Connection.WaitForDisk(bootableDiskAttachment.MustId(), ovirtsdk.DISKSTATUS_OK, timeout)
 		if err != nil {
 			return err
 		}
@@ -229,46 +590,122 @@ func (is *InstanceService) handleDiskExtension(vmService *ovirtsdk.VmService, cr
 	return nil
 }
 
-func (is *InstanceService) InstanceDelete(id string) error {
-	klog.Infof("Deleting VM with ID: %s", id)
+// InstanceDelete moves the VM deletion to its next step without waiting for it:
+// a running VM is stopped and a stopped VM is removed. An InProgressError is
+// returned while the VM is being stopped, the caller should call again later.
+// Until the shutdown deadline the guest is shut down gracefully, then the VM is
+// powered off; with a zero deadline the VM is powered off right away.
+// With force, a VM being shut down is powered off instead of waited for, and
+// the removal is forced. The vm is the one fetched by the current reconcile.
+// The delete protection of the VM is cleared, so InstanceDelete must only be
+// called for the deletion of the machine of the VM.
+func (is *InstanceService) InstanceDelete(vm *ovirtsdk.Vm, force bool, shutdownDeadline time.Time) error {
+	id := vm.MustId()
 	vmService := is.Connection.SystemService().VmsService().VmService(id)
-	_, err := vmService.Stop().Send()
-	if err != nil {
-		return err
-	}
-	err = util.PollImmediate(time.Second*10, time.Minute*5, func() (bool, error) {
-		vmResponse, err := vmService.Get().Send()
-		if err != nil {
-			return false, nil
+	switch status := vm.MustStatus(); {
+	case status == ovirtsdk.VMSTATUS_DOWN:
+	case status == ovirtsdk.VMSTATUS_IMAGE_LOCKED:
+		// the VM disks are being created or removed
+		return &InProgressError{Operation: fmt.Sprintf("an operation on the disks of VM %s", id)}
+	case !force && time.Now().Before(shutdownDeadline):
+		if status != ovirtsdk.VMSTATUS_POWERING_DOWN {
+			klog.Infof("Shutting down VM with ID: %s", id)
+			is.reportDeleteProgress("ShuttingDown", fmt.Sprintf(
+				"Shutting down VM %s gracefully, it is powered off if still running at %s",
+				id, shutdownDeadline.Format(time.RFC3339)))
+			if _, err := vmService.Shutdown().Send(); err != nil {
+				return err
+			}
 		}
-		vm, ok := vmResponse.Vm()
-		if !ok {
-			return false, err
+		return &InProgressError{Operation: fmt.Sprintf("shutting down VM %s", id)}
+	case status == ovirtsdk.VMSTATUS_POWERING_DOWN && !force && shutdownDeadline.IsZero():
+		return &InProgressError{Operation: fmt.Sprintf("stopping VM %s", id)}
+	default:
+		klog.Infof("Stopping VM with ID: %s", id)
+		if !force && !shutdownDeadline.IsZero() {
+			is.reportDeleteProgress("PoweringOff", fmt.Sprintf(
+				"VM %s didn't shut down gracefully in time, powering it off", id))
+		} else {
+			is.reportDeleteProgress("Stopping", fmt.Sprintf("Stopping VM %s", id))
 		}
+		if _, err := vmService.Stop().Send(); err != nil {
+			return err
+		}
+		return &InProgressError{Operation: fmt.Sprintf("stopping VM %s", id)}
+	}
 
-		return vm.MustStatus() == ovirtsdk.VMSTATUS_DOWN, nil
-	})
-	_, err = vmService.Remove().Send()
-
-	// poll till VM doesn't exist
-	err = util.PollImmediate(time.Second*10, time.Minute*5, func() (bool, error) {
-		_, err := vmService.Get().Send()
-		return err != nil, nil
-	})
+	if err := clearDeleteProtection(vmService, vm); err != nil {
+		return err
+	}
+	klog.Infof("Deleting VM with ID: %s", id)
+	is.reportDeleteProgress("Removing", fmt.Sprintf("Removing VM %s", id))
+	_, err := vmService.Remove().Force(force).Send()
 	return err
 }
 
-// Get VM by ID or Name
+// clearDeleteProtection clears the delete protection of the VM, if set, so it can be removed
+func clearDeleteProtection(vmService *ovirtsdk.VmService, vm *ovirtsdk.Vm) error {
+	if protected, ok := vm.DeleteProtected(); !ok || !protected {
+		return nil
+	}
+	klog.Infof("Clearing the delete protection of VM %s", vm.MustName())
+	_, err := vmService.Update().Vm(ovirtsdk.NewVmBuilder().DeleteProtected(false).MustBuild()).Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed clearing the delete protection of VM %s", vm.MustName())
+	}
+	return nil
+}
+
+func (is *InstanceService) reportDeleteProgress(reason, message string) {
+	if is.OnDeleteProgress != nil {
+		is.OnDeleteProgress(reason, message)
+	}
+}
+
+// ProvisionedDiskSize returns the sum of the provisioned sizes of the VM disks in bytes
+func (is *InstanceService) ProvisionedDiskSize(id string) (int64, error) {
+	res, err := is.Connection.SystemService().VmsService().VmService(id).
+		DiskAttachmentsService().List().Follow("disk").Send()
+	if err != nil {
+		return 0, err
+	}
+	var size int64
+	for _, attachment := range res.MustAttachments().Slice() {
+		if disk, ok := attachment.Disk(); ok {
+			if provisioned, ok := disk.ProvisionedSize(); ok {
+				size += provisioned
+			}
+		}
+	}
+	return size, nil
+}
+
+// VCPUs returns the number of virtual CPUs of the VM
+func VCPUs(vm *ovirtsdk.Vm) int64 {
+	cpu, ok := vm.Cpu()
+	if !ok {
+		return 0
+	}
+	topology, ok := cpu.Topology()
+	if !ok {
+		return 0
+	}
+	return topology.MustSockets() * topology.MustCores() * topology.MustThreads()
+}
+
+// GetVm returns the VM of the machine by its provider ID, or by the machine name if the
+// machine has no provider ID or its VM is gone. A nil instance is returned only when the
+// engine reports there is no such VM, other errors are returned so a VM which can't be
+// fetched isn't taken as removed.
 func (is *InstanceService) GetVm(machine machinev1.Machine) (instance *Instance, err error) {
 	if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
-		instance, err = is.GetVmByID(*machine.Spec.ProviderID)
-		if err == nil {
+		instance, err = is.GetVmByID(strings.TrimPrefix(*machine.Spec.ProviderID, ovirt.ProviderIDPrefix))
+		if _, notFound := err.(*ovirtsdk.NotFoundError); err == nil || !notFound {
 			return instance, err
 		}
 	}
 	instance, err = is.GetVmByName()
 	return instance, err
-
 }
 
 func (is *InstanceService) GetVmByID(resourceId string) (instance *Instance, err error) {
@@ -284,9 +721,15 @@ func (is *InstanceService) GetVmByID(resourceId string) (instance *Instance, err
 	return &Instance{Vm: response.MustVm()}, nil
 }
 
+// GetVmByName returns the VM named after the machine, or nil if there is none. An
+// OwnershipConflictError is returned if the VM is owned by another machine.
 func (is *InstanceService) GetVmByName() (*Instance, error) {
+	query, err := is.vmNameSearch(is.MachineName, is.ClusterId)
+	if err != nil {
+		return nil, err
+	}
 	response, err := is.Connection.SystemService().VmsService().
-		List().Search("name=" + is.MachineName).Send()
+		List().Search(query).Send()
 	if err != nil {
 		klog.Errorf("Failed to fetch VM by name")
 		return nil, err
@@ -294,6 +737,10 @@ func (is *InstanceService) GetVmByName() (*Instance, error) {
 	for _, vm := range response.MustVms().Slice() {
 		if name, ok := vm.Name(); ok {
 			if name == is.MachineName {
+				// a VM with the machine name may be left over by a deleted machine of the same name
+				if err := is.VerifyOwner(vm); err != nil {
+					return nil, err
+				}
 				return &Instance{Vm: vm}, nil
 			}
 		}
@@ -302,6 +749,9 @@ func (is *InstanceService) GetVmByName() (*Instance, error) {
 	return nil, nil
 }
 
+// handleNics replaces the template network interfaces with the ones in the spec.
+// Interfaces which already match the spec are kept, so repeated calls don't
+// flap the guest networking.
 func (is *InstanceService) handleNics(vmService *ovirtsdk.VmService, spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
 	if spec.NetworkInterfaces == nil || len(spec.NetworkInterfaces) == 0 {
 		return nil
@@ -311,47 +761,111 @@ func (is *InstanceService) handleNics(vmService *ovirtsdk.VmService, spec *ovirt
 		return errors.Wrap(err, "failed fetching VM network interfaces")
 	}
 
-	// remove all existing nics
+	desired := make([]*ovirtsdk.Nic, len(spec.NetworkInterfaces))
+	for i, nic := range spec.NetworkInterfaces {
+		profileID, err := is.vnicProfileID(nic, spec.ClusterId)
+		if err != nil {
+			return err
+		}
+		if err := is.checkNetworkFilter(nic, profileID); err != nil {
+			return err
+		}
+		name := nic.Name
+		if name == "" {
+			name = fmt.Sprintf("nic%d", i+1)
+		}
+		desired[i] = ovirtsdk.NewNicBuilder().
+			Name(name).
+			VnicProfileBuilder(ovirtsdk.NewVnicProfileBuilder().Id(profileID)).
+			MustBuild()
+	}
+
+	// remove the existing nics which don't match the spec
+	nicIDs := make(map[string]string)
 	for _, n := range nicList.MustNics().Slice() {
+		if matchingNic(n, desired) {
+			nicIDs[n.MustName()] = n.MustId()
+			continue
+		}
 		_, err := vmService.NicsService().NicService(n.MustId()).Remove().Send()
 		if err != nil {
 			return errors.Wrap(err, "failed clearing all interfaces before populating new ones")
 		}
 	}
 
-	// re-add nics
-	for i, nic := range spec.NetworkInterfaces {
-		_, err := vmService.NicsService().Add().Nic(
-			ovirtsdk.NewNicBuilder().
-				Name(fmt.Sprintf("nic%d", i+1)).
-				VnicProfileBuilder(ovirtsdk.NewVnicProfileBuilder().Id(nic.VNICProfileID)).
-				MustBuild()).
-			Send()
+	// add the missing nics
+	for _, nic := range desired {
+		if _, ok := nicIDs[nic.MustName()]; ok {
+			klog.V(5).Infof("network interface %s already exists, skipping", nic.MustName())
+			continue
+		}
+		res, err := vmService.NicsService().Add().Nic(nic).Send()
 		if err != nil {
 			return errors.Wrap(err, "failed to create network interface")
 		}
+		nicIDs[nic.MustName()] = res.MustNic().MustId()
+	}
+
+	// the nics inherited from the template don't have the network filter parameters of the spec
+	for i, nic := range desired {
+		parameters := spec.NetworkInterfaces[i].NetworkFilterParameters
+		if len(parameters) == 0 {
+			continue
+		}
+		err := reconcileNetworkFilterParameters(vmService.NicsService().NicService(nicIDs[nic.MustName()]), parameters)
+		if err != nil {
+			return errors.Wrapf(err, "failed setting the network filter parameters of network interface %s",
+				nic.MustName())
+		}
 	}
 	return nil
 }
 
-//Find virtual machine IP Address by ID
-func (is *InstanceService) FindVirtualMachineIP(id string, excludeAddr map[string]int) (string, error) {
+// matchingNic returns true if the existing nic has the name and vNIC profile of one of the desired nics
+func matchingNic(existing *ovirtsdk.Nic, desired []*ovirtsdk.Nic) bool {
+	name, ok := existing.Name()
+	if !ok {
+		return false
+	}
+	profile, ok := existing.VnicProfile()
+	if !ok {
+		return false
+	}
+	for _, nic := range desired {
+		if nic.MustName() == name && nic.MustVnicProfile().MustId() == profile.MustId() {
+			return true
+		}
+	}
+	return false
+}
+
+// FindVirtualMachineIPs returns the usable IP addresses the guest agent reports on the
+// ethernet NICs of the VM, skipping the excluded and the link-local addresses. They are
+// ordered deterministically: the addresses of the preferred family first, if any, then
+// by NIC name and address, so the first one doesn't change across reboots.
+func (is *InstanceService) FindVirtualMachineIPs(id string, excludeAddr map[string]int, family string) ([]string, error) {
 
 	vmService := is.Connection.SystemService().VmsService().VmService(id)
 
 	// Get the guest reported devices
 	reportedDeviceResp, err := vmService.ReportedDevicesService().List().Send()
 	if err != nil {
-		return "", fmt.Errorf("failed to get reported devices list, reason: %v", err)
+		return nil, fmt.Errorf("failed to get reported devices list, reason: %v", err)
 	}
 	reportedDeviceSlice, _ := reportedDeviceResp.ReportedDevice()
 
 	if len(reportedDeviceSlice.Slice()) == 0 {
-		return "", fmt.Errorf("cannot find NICs for vmId: %s", id)
+		return nil, fmt.Errorf("cannot find NICs for vmId: %s", id)
 	}
 
 	var nicRegex = regexp.MustCompile(`^(eth|en).*`)
 
+	type nicAddress struct {
+		nic       string
+		address   string
+		preferred bool
+	}
+	var found []nicAddress
 	for _, reportedDevice := range reportedDeviceSlice.Slice() {
 		nicName, _ := reportedDevice.Name()
 		if !nicRegex.MatchString(nicName) {
@@ -360,30 +874,95 @@ func (is *InstanceService) FindVirtualMachineIP(id string, excludeAddr map[strin
 		}
 
 		ips, hasIps := reportedDevice.Ips()
-		if hasIps {
-			for _, ip := range ips.Slice() {
-				ipAddress, hasAddress := ip.Address()
+		if !hasIps {
+			continue
+		}
+		for _, ip := range ips.Slice() {
+			ipAddress, hasAddress := ip.Address()
+			if !hasAddress {
+				continue
+			}
+			if _, ok := excludeAddr[ipAddress]; ok {
+				klog.Infof("ipAddress %s is excluded from usable IPs", ipAddress)
+				continue
+			}
+			parsed := net.ParseIP(ipAddress)
+			if parsed == nil || parsed.IsLinkLocalUnicast() {
+				klog.V(5).Infof("ipAddress %s of nic %s isn't usable, skipped", ipAddress, nicName)
+				continue
+			}
+			klog.V(5).Infof("ovirt vm id: %s , found usable IP %s", id, ipAddress)
+			found = append(found, nicAddress{nic: nicName, address: ipAddress, preferred: inFamily(parsed, family)})
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("coudlnt find usable IP address for vm id: %s", id)
+	}
 
-				if _, ok := excludeAddr[ipAddress]; ok {
-					klog.Infof("ipAddress %s is excluded from usable IPs", ipAddress)
-					continue
-				}
+	sort.Slice(found, func(i, j int) bool {
+		if found[i].preferred != found[j].preferred {
+			return found[i].preferred
+		}
+		if found[i].nic != found[j].nic {
+			return found[i].nic < found[j].nic
+		}
+		return found[i].address < found[j].address
+	})
+	addresses := make([]string, 0, len(found))
+	seen := make(map[string]bool, len(found))
+	for _, f := range found {
+		if !seen[f.address] {
+			seen[f.address] = true
+			addresses = append(addresses, f.address)
+		}
+	}
+	return addresses, nil
+}
 
-				if hasAddress {
-					klog.Infof("ovirt vm id: %s , found usable IP %s", id, ipAddress)
-					return ipAddress, nil
-				}
+// inFamily returns true if the IP is of the address family, any IP is if no family is given
+func inFamily(ip net.IP, family string) bool {
+	switch family {
+	case ovirtconfigv1.AddressFamilyIPv4:
+		return ip.To4() != nil
+	case ovirtconfigv1.AddressFamilyIPv6:
+		return ip.To4() == nil
+	}
+	return true
+}
+
+// getPreferredHosts returns the hosts of the cluster which match the given selector
+func (is *InstanceService) getPreferredHosts(cID string, selector *ovirtconfigv1.HostSelector) ([]*ovirtsdk.Host, error) {
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []*ovirtsdk.Host
+	seen := make(map[string]bool)
+	for _, tag := range selector.Tags {
+		res, err := is.Connection.SystemService().HostsService().
+			List().Search(scope.search("tag=" + searchValue(tag))).Send()
+		if err != nil {
+			return nil, err
+		}
+		for _, host := range res.MustHosts().Slice() {
+			cluster, ok := host.Cluster()
+			if !ok || cluster.MustId() != cID || seen[host.MustId()] {
+				continue
 			}
+			seen[host.MustId()] = true
+			hosts = append(hosts, ovirtsdk.NewHostBuilder().Id(host.MustId()).MustBuild())
 		}
 	}
-	return "", fmt.Errorf("coudlnt find usable IP address for vm id: %s", id)
+	return hosts, nil
 }
 
-func (is *InstanceService) getAffinityGroups(cID string, agNames []string) (ag []*ovirtsdk.AffinityGroup, err error) {
+// getAffinityGroups returns the affinity groups of the names in the oVirt cluster. A missing
+// group is created if create is set, marked with the OpenShift cluster ID, else it is an error.
+func (is *InstanceService) getAffinityGroups(cID string, agNames []string, create bool, clusterID string) (ag []*ovirtsdk.AffinityGroup, err error) {
 	var ags []*ovirtsdk.AffinityGroup
-	res, err := is.Connection.SystemService().ClustersService().
-		ClusterService(cID).AffinityGroupsService().
-		List().Send()
+	agService := is.Connection.SystemService().ClustersService().
+		ClusterService(cID).AffinityGroupsService()
+	res, err := agService.List().Send()
 	if err != nil {
 		return nil, err
 	}
@@ -393,16 +972,30 @@ func (is *InstanceService) getAffinityGroups(cID string, agNames []string) (ag [
 	}
 	for _, agName := range agNames {
 		if _, ok := agNamesMap[agName]; !ok {
-			return nil, errors.Errorf("affinity group %v was not found on cluster %v", agName, cID)
+			if !create {
+				return nil, errors.Errorf("affinity group %v was not found on cluster %v", agName, cID)
+			}
+			klog.Infof("Creating affinity group %s", agName)
+			addRes, err := agService.Add().Group(ovirtsdk.NewAffinityGroupBuilder().
+				Name(agName).
+				Description(affinityGroupDescription(clusterID)).
+				VmsRuleBuilder(ovirtsdk.NewAffinityRuleBuilder().Enabled(true).Enforcing(false).Positive(false)).
+				MustBuild()).Send()
+			if err != nil {
+				return nil, errors.Wrapf(err, "failed creating affinity group %s", agName)
+			}
+			agNamesMap[agName] = addRes.MustGroup()
 		}
 		ags = append(ags, agNamesMap[agName])
 	}
 	return ags, nil
 }
 
-// handleAffinityGroups adds the VM to the provided affinity groups
-func (is *InstanceService) handleAffinityGroups(vm *ovirtsdk.Vm, cID string, agsName []string) error {
-	ags, err := is.getAffinityGroups(cID, agsName)
+// handleAffinityGroups adds the VM to the affinity groups of the provider spec
+func (is *InstanceService) handleAffinityGroups(vm *ovirtsdk.Vm, machine *machinev1.Machine, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	cID := providerSpec.ClusterId
+	ags, err := is.getAffinityGroups(cID, providerSpec.AffinityGroupsNames,
+		providerSpec.CreateMissingAffinityGroups, machine.Labels[clusterIDLabel])
 	if err != nil {
 		return err
 	}
@@ -413,7 +1006,7 @@ func (is *InstanceService) handleAffinityGroups(vm *ovirtsdk.Vm, cID string, ags
 		_, err = agService.GroupService(ag.MustId()).VmsService().Add().Vm(vm).Send()
 
 		// TODO: bug 1932320: Remove error handling workaround when BZ#1931932 is resolved and backported
-		if err != nil && !errors.Is(err, ovirtsdk.XMLTagNotMatchError{"action", "vm"}) {
+		if err != nil && !errors.Is(err, ovirtsdk.XMLTagNotMatchError{ActualTag: "action", ExpectedTag: "vm"}) {
 			return errors.Errorf(
 				"failed to add VM %s to AffinityGroup %s, error: %v",
 				vm.MustName(),
@@ -423,3 +1016,54 @@ func (is *InstanceService) handleAffinityGroups(vm *ovirtsdk.Vm, cID string, ags
 	}
 	return nil
 }
+
+// ReleaseAffinityGroups removes the VM from the enforcing VM affinity groups of its
+// cluster before it is stopped, so the engine rebalances its peers under the remaining
+// hard rules instead of failing the operations on the VM being deleted.
+func (is *InstanceService) ReleaseAffinityGroups(vm *ovirtsdk.Vm) error {
+	cluster, ok := vm.Cluster()
+	if !ok {
+		return nil
+	}
+	agService := is.Connection.SystemService().ClustersService().
+		ClusterService(cluster.MustId()).AffinityGroupsService()
+	res, err := agService.List().Follow("vms").Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed listing the affinity groups of VM %s", vm.MustName())
+	}
+	for _, ag := range res.MustGroups().Slice() {
+		if !enforcingVMsRule(ag) || !groupHasVM(ag, vm.MustId()) {
+			continue
+		}
+		klog.Infof("Removing machine %v from affinity group %v", vm.MustName(), ag.MustName())
+		_, err := agService.GroupService(ag.MustId()).VmsService().VmService(vm.MustId()).Remove().Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed removing VM %s from affinity group %s", vm.MustName(), ag.MustName())
+		}
+	}
+	return nil
+}
+
+// enforcingVMsRule returns true if the VMs rule of the affinity group is enforced
+func enforcingVMsRule(ag *ovirtsdk.AffinityGroup) bool {
+	if rule, ok := ag.VmsRule(); ok {
+		enabled, _ := rule.Enabled()
+		enforcing, _ := rule.Enforcing()
+		return enabled && enforcing
+	}
+	enforcing, _ := ag.Enforcing()
+	return enforcing
+}
+
+func groupHasVM(ag *ovirtsdk.AffinityGroup, vmID string) bool {
+	vms, ok := ag.Vms()
+	if !ok {
+		return false
+	}
+	for _, vm := range vms.Slice() {
+		if id, ok := vm.Id(); ok && id == vmID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,30 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+)
+
+// MigrateInstance back-fills on a VM created by an older provider version what the
+// current version records on the VMs it creates: the UID of the owning machine in
+// the VM comment, and the OpenShift cluster tag
+func (is *InstanceService) MigrateInstance(machine *machinev1.Machine, vm *ovirtsdk.Vm) error {
+	vmService := is.Connection.SystemService().VmsService().VmService(vm.MustId())
+	if MachineUID(vm) == "" {
+		existing, _ := vm.Comment()
+		comment := ownerComment(existing, machine.UID)
+		_, err := vmService.Update().Vm(ovirtsdk.NewVmBuilder().Comment(comment).MustBuild()).Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed recording the owner of VM %s", vm.MustName())
+		}
+	}
+	is.handleTags(vmService, machine)
+	return nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidateNUMANodes checks the virtual NUMA nodes of the spec
+func ValidateNUMANodes(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	indexes := make(map[int32]bool, len(spec.NUMANodes))
+	cores := make(map[int32]bool)
+	var memoryMB int32
+	for _, node := range spec.NUMANodes {
+		if node.Index < 0 || indexes[node.Index] {
+			return fmt.Errorf("invalid or duplicate NUMA node index %d", node.Index)
+		}
+		indexes[node.Index] = true
+		if len(node.Cores) == 0 {
+			return fmt.Errorf("NUMA node %d has no cores", node.Index)
+		}
+		for _, core := range node.Cores {
+			if core < 0 || cores[core] {
+				return fmt.Errorf("invalid or duplicate core %d on NUMA node %d", core, node.Index)
+			}
+			cores[core] = true
+		}
+		if node.MemoryMB <= 0 {
+			return fmt.Errorf("NUMA node %d has no memory", node.Index)
+		}
+		memoryMB += node.MemoryMB
+		switch ovirtsdk.NumaTuneMode(node.TuneMode) {
+		case "", ovirtsdk.NUMATUNEMODE_STRICT, ovirtsdk.NUMATUNEMODE_INTERLEAVE, ovirtsdk.NUMATUNEMODE_PREFERRED:
+		default:
+			return fmt.Errorf("NUMA node %d tune mode must be %s, %s or %s, got %s", node.Index,
+				ovirtsdk.NUMATUNEMODE_STRICT, ovirtsdk.NUMATUNEMODE_INTERLEAVE, ovirtsdk.NUMATUNEMODE_PREFERRED, node.TuneMode)
+		}
+		if len(node.HostNodes) > 0 && !hostsSelected(spec) {
+			return fmt.Errorf("NUMA node %d is pinned to host NUMA nodes, placement or preferred hosts are required", node.Index)
+		}
+	}
+	if spec.MemoryMB > 0 && memoryMB > spec.MemoryMB {
+		return fmt.Errorf("the NUMA nodes memory %d MiB exceeds the VM memory %d MiB", memoryMB, spec.MemoryMB)
+	}
+	return nil
+}
+
+// handleNUMANodes adds the virtual NUMA nodes of the spec to the VM. The nodes
+// which already exist, added before an interrupted creation, are skipped.
+func (is *InstanceService) handleNUMANodes(vmService *ovirtsdk.VmService, nodes []ovirtconfigv1.NUMANode) error {
+	if len(nodes) == 0 {
+		return nil
+	}
+	numaService := vmService.NumaNodesService()
+	res, err := numaService.List().Send()
+	if err != nil {
+		return errors.Wrap(err, "failed listing the VM NUMA nodes")
+	}
+	existing := make(map[int64]bool)
+	for _, node := range res.MustNodes().Slice() {
+		existing[node.MustIndex()] = true
+	}
+	for _, node := range nodes {
+		if existing[int64(node.Index)] {
+			klog.V(5).Infof("NUMA node %d of VM %s already exists, skipping", node.Index, is.MachineName)
+			continue
+		}
+		_, err := numaService.Add().Node(virtualNUMANode(node)).Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed adding NUMA node %d", node.Index)
+		}
+	}
+	return nil
+}
+
+// virtualNUMANode returns the engine virtual NUMA node of the spec node
+func virtualNUMANode(node ovirtconfigv1.NUMANode) *ovirtsdk.VirtualNumaNode {
+	cores := make([]*ovirtsdk.Core, 0, len(node.Cores))
+	for _, core := range node.Cores {
+		cores = append(cores, ovirtsdk.NewCoreBuilder().Index(int64(core)).MustBuild())
+	}
+	builder := ovirtsdk.NewVirtualNumaNodeBuilder().
+		Index(int64(node.Index)).
+		Memory(int64(node.MemoryMB)).
+		CpuBuilder(ovirtsdk.NewCpuBuilder().CoresOfAny(cores...))
+	for _, hostNode := range node.HostNodes {
+		builder.NumaNodePinsOfAny(ovirtsdk.NewNumaNodePinBuilder().
+			Pinned(true).
+			HostNumaNodeBuilder(ovirtsdk.NewNumaNodeBuilder().Index(int64(hostNode))).
+			MustBuild())
+	}
+	if node.TuneMode != "" {
+		builder.NumaTuneMode(ovirtsdk.NumaTuneMode(node.TuneMode))
+	}
+	return builder.MustBuild()
+}
@@ -0,0 +1,86 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"strings"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+const (
+	// machineUIDMarker prefixes the UID of the owning machine in the VM comment.
+	// Unlike the VM name and tags, the UID can't be reused by another machine.
+	machineUIDMarker = "machine.openshift.io/uid="
+
+	// clusterIDLabel is the machine label holding the OpenShift cluster ID
+	clusterIDLabel = "machine.openshift.io/cluster-api-cluster"
+	// machineSetLabel is the machine label holding the name of its MachineSet
+	machineSetLabel = "machine.openshift.io/cluster-api-machineset"
+
+	// affinityGroupMarker prefixes the OpenShift cluster ID in the description of the
+	// affinity groups the provider created, the teardown of the cluster removes them
+	affinityGroupMarker = "machine.openshift.io/cluster-api-cluster="
+)
+
+// ownerComment returns the VM comment recording the owning machine, after the given comment
+func ownerComment(comment string, uid types.UID) string {
+	if comment == "" {
+		return machineUIDMarker + string(uid)
+	}
+	return comment + " " + machineUIDMarker + string(uid)
+}
+
+// affinityGroupDescription returns the description marking an affinity group created
+// for the OpenShift cluster with the ID
+func affinityGroupDescription(clusterID string) string {
+	return affinityGroupMarker + clusterID
+}
+
+// expandMachineText replaces the {cluster_id}, {machineset} and {machine} placeholders
+// of the text by the OpenShift cluster ID, the MachineSet name and the name of the machine
+func expandMachineText(text string, machine *machinev1.Machine) string {
+	if !strings.Contains(text, "{") {
+		return text
+	}
+	return strings.NewReplacer(
+		"{cluster_id}", machine.Labels[clusterIDLabel],
+		"{machineset}", machine.Labels[machineSetLabel],
+		"{machine}", machine.Name,
+	).Replace(text)
+}
+
+// MachineUID returns the UID of the machine owning the VM, or an empty string
+// if the VM doesn't record its owner, e.g it was created by an older version.
+func MachineUID(vm *ovirtsdk.Vm) types.UID {
+	comment, ok := vm.Comment()
+	if !ok {
+		return ""
+	}
+	i := strings.Index(comment, machineUIDMarker)
+	if i < 0 {
+		return ""
+	}
+	uid := comment[i+len(machineUIDMarker):]
+	if end := strings.IndexAny(uid, " ;\n"); end >= 0 {
+		uid = uid[:end]
+	}
+	return types.UID(uid)
+}
+
+// VerifyOwner returns an OwnershipConflictError if the VM records an owning machine
+// other than the machine of the instance service
+func (is *InstanceService) VerifyOwner(vm *ovirtsdk.Vm) error {
+	owner := MachineUID(vm)
+	if owner == "" || is.MachineUID == "" || owner == is.MachineUID {
+		return nil
+	}
+	return &OwnershipConflictError{msg: fmt.Sprintf("VM %s(%s) is owned by machine UID %s, not by machine %s(%s)",
+		vm.MustName(), vm.MustId(), owner, is.MachineName, is.MachineUID)}
+}
@@ -0,0 +1,110 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"testing"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func TestOwnerComment(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		comment string
+		uid     types.UID
+		want    string
+	}{
+		{
+			name: "no comment",
+			uid:  "1234",
+			want: "machine.openshift.io/uid=1234",
+		},
+		{
+			name:    "after the comment",
+			comment: "worker of cluster c1",
+			uid:     "1234",
+			want:    "worker of cluster c1 machine.openshift.io/uid=1234",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ownerComment(tc.comment, tc.uid); got != tc.want {
+				t.Errorf("ownerComment() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestMachineUID(t *testing.T) {
+	vm := func(comment string) *ovirtsdk.Vm {
+		return ovirtsdk.NewVmBuilder().Comment(comment).MustBuild()
+	}
+	for _, tc := range []struct {
+		name string
+		vm   *ovirtsdk.Vm
+		want types.UID
+	}{
+		{
+			name: "no comment",
+			vm:   ovirtsdk.NewVmBuilder().MustBuild(),
+		},
+		{
+			name: "comment without owner",
+			vm:   vm("created by hand"),
+		},
+		{
+			name: "owner only",
+			vm:   vm("machine.openshift.io/uid=1234"),
+			want: "1234",
+		},
+		{
+			name: "owner after the comment",
+			vm:   vm("worker of cluster c1 machine.openshift.io/uid=1234"),
+			want: "1234",
+		},
+		{
+			name: "comment after the owner",
+			vm:   vm("machine.openshift.io/uid=1234; edited by admin"),
+			want: "1234",
+		},
+		{
+			name: "owner on its own line",
+			vm:   vm("machine.openshift.io/uid=1234\nnotes"),
+			want: "1234",
+		},
+		{
+			name: "round trip",
+			vm:   vm(ownerComment("some comment", "5678")),
+			want: "5678",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := MachineUID(tc.vm); got != tc.want {
+				t.Errorf("MachineUID() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVerifyOwner(t *testing.T) {
+	is := &InstanceService{MachineName: "worker-0", MachineUID: "1234"}
+	vm := func(comment string) *ovirtsdk.Vm {
+		return ovirtsdk.NewVmBuilder().Id("vm-0").Name("worker-0").Comment(comment).MustBuild()
+	}
+	if err := is.VerifyOwner(vm(ownerComment("", "1234"))); err != nil {
+		t.Errorf("VerifyOwner() of a VM of the machine = %v, want nil", err)
+	}
+	if err := is.VerifyOwner(vm("created by hand")); err != nil {
+		t.Errorf("VerifyOwner() of a VM without an owner = %v, want nil", err)
+	}
+	if err := is.VerifyOwner(vm(ownerComment("", "5678"))); !IsOwnershipConflict(err) {
+		t.Errorf("VerifyOwner() of a VM of another machine = %v, want an ownership conflict", err)
+	}
+}
@@ -0,0 +1,169 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+	apicorev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/metrics"
+)
+
+// RequiredPermits are the engine permits the provider uses to manage the machine VMs.
+// They are all granted on the cluster and the storage domains of the machines, except
+// tag_management, which the engine grants only system wide.
+var RequiredPermits = []string{
+	"login",
+	"create_vm",
+	"delete_vm",
+	"edit_vm_properties",
+	// the CPU pinning, the NUMA nodes and the placement policy of the VMs
+	"edit_admin_vm_properties",
+	// the custom properties of the VMs
+	"change_vm_custom_properties",
+	"vm_basic_operations",
+	"run_vm",
+	"shut_down_vm",
+	"stop_vm",
+	"configure_vm_network",
+	"configure_vm_storage",
+	"create_disk",
+	"edit_disk_properties",
+	// the copies of the template disks pre-warming the storage domains of the machines
+	"configure_disk_storage",
+	// the console access granted to the users of the provider spec
+	"manipulate_permissions",
+	"manipulate_affinity_groups",
+	"tag_management",
+}
+
+// MissingPermits returns the required permits which none of the roles of the
+// authenticated user, directly or through its groups, grant.
+func MissingPermits(connection *ovirtsdk.Connection) ([]string, error) {
+	api, err := connection.SystemService().Get().Send()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed fetching the authenticated user")
+	}
+	user, ok := api.MustApi().AuthenticatedUser()
+	if !ok {
+		return nil, errors.New("the engine didn't report the authenticated user")
+	}
+	userService := connection.SystemService().UsersService().UserService(user.MustId())
+	res, err := userService.PermissionsService().List().Send()
+	if err != nil {
+		return nil, errors.Wrap(err, "failed listing the user permissions")
+	}
+	permissions := res.MustPermissions().Slice()
+
+	groups, err := userService.GroupsService().List().Send()
+	if err != nil {
+		klog.V(3).Infof("failed listing the groups of the user, checking only its own permissions: %v", err)
+	} else {
+		for _, group := range groups.MustGroups().Slice() {
+			res, err := connection.SystemService().GroupsService().GroupService(group.MustId()).
+				PermissionsService().List().Send()
+			if err != nil {
+				// the directory group may have no engine permissions at all
+				continue
+			}
+			permissions = append(permissions, res.MustPermissions().Slice()...)
+		}
+	}
+
+	granted := make(map[string]bool)
+	roles := make(map[string]bool)
+	for _, p := range permissions {
+		role, ok := p.Role()
+		if !ok || roles[role.MustId()] {
+			continue
+		}
+		roles[role.MustId()] = true
+		permits, err := connection.SystemService().RolesService().RoleService(role.MustId()).
+			PermitsService().List().Send()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed listing the permits of role %s", role.MustId())
+		}
+		for _, permit := range permits.MustPermits().Slice() {
+			granted[permit.MustName()] = true
+		}
+	}
+
+	var missing []string
+	for _, permit := range RequiredPermits {
+		if !granted[permit] {
+			missing = append(missing, permit)
+		}
+	}
+	return missing, nil
+}
+
+// PermissionsChecker probes the permits granted by the credentials secrets, once
+// per version of each secret. It is safe for concurrent use.
+type PermissionsChecker struct {
+	client client.Client
+
+	mu     sync.Mutex
+	probed map[types.NamespacedName]permissionsProbe
+}
+
+type permissionsProbe struct {
+	resourceVersion string
+	missing         []string
+}
+
+// NewPermissionsChecker returns a permissions checker reading the credentials secrets with the client
+func NewPermissionsChecker(client client.Client) *PermissionsChecker {
+	return &PermissionsChecker{
+		client: client,
+		probed: make(map[types.NamespacedName]permissionsProbe),
+	}
+}
+
+// Check returns the required permits missing for the user of the credentials secret.
+// The engine is probed again only when the secret changed since the last probe.
+func (c *PermissionsChecker) Check(namespace, secretName string, connection *ovirtsdk.Connection) ([]string, error) {
+	key := types.NamespacedName{Namespace: namespace, Name: secretName}
+	var secret apicorev1.Secret
+	if err := c.client.Get(context.Background(), key, &secret); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous, ok := c.probed[key]
+	if ok && previous.resourceVersion == secret.ResourceVersion {
+		return previous.missing, nil
+	}
+	missing, err := MissingPermits(connection)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(missing)
+	c.probed[key] = permissionsProbe{resourceVersion: secret.ResourceVersion, missing: missing}
+
+	for _, permit := range previous.missing {
+		metrics.MissingPermits.DeleteLabelValues(key.String(), permit)
+	}
+	for _, permit := range missing {
+		metrics.MissingPermits.WithLabelValues(key.String(), permit).Set(1)
+	}
+	if len(missing) > 0 {
+		klog.Warningf("The engine user of credentials secret %s is missing the permits %v", key, missing)
+	} else {
+		klog.Infof("The engine user of credentials secret %s has all the required permits", key)
+	}
+	return missing, nil
+}
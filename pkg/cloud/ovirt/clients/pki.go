@@ -0,0 +1,109 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"k8s.io/klog"
+)
+
+// pkiResourcePath is the engine endpoint serving its CA certificate
+const pkiResourcePath = "/ovirt-engine/services/pki-resource?resource=ca-certificate&format=X509-PEM-CA"
+
+// pkiFetchTimeout bounds the download of the engine CA certificate
+const pkiFetchTimeout = 30 * time.Second
+
+// engineCAs caches the engine CA certificates fetched from the pki-resource endpoint,
+// keyed by the engine host and the pinned fingerprint
+var engineCAs sync.Map
+
+// fetchEngineCA returns the PEM CA certificate of the engine at engineURL, downloaded
+// from its pki-resource endpoint. The download can't verify the engine certificate, so
+// the CA certificate is trusted only if its SHA-256 fingerprint matches the pinned one,
+// and a response holding anything else is rejected. The certificate is cached for the
+// lifetime of the process.
+func fetchEngineCA(engineURL, fingerprint string) ([]byte, error) {
+	u, err := url.Parse(engineURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid engine URL %q: %v", engineURL, err)
+	}
+	pinned := normalizeFingerprint(fingerprint)
+	key := u.Host + "/" + pinned
+	if ca, ok := engineCAs.Load(key); ok {
+		return ca.([]byte), nil
+	}
+
+	pkiURL := url.URL{Scheme: u.Scheme, Host: u.Host}
+	klog.Infof("Fetching the engine CA certificate from %s", pkiURL.String())
+	httpClient := &http.Client{
+		Timeout: pkiFetchTimeout,
+		Transport: &http.Transport{
+			Proxy: http.ProxyFromEnvironment,
+			// the fetched certificate is verified by its pinned fingerprint
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	res, err := httpClient.Get(pkiURL.String() + pkiResourcePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed fetching the engine CA certificate: %v", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed fetching the engine CA certificate: %s", res.Status)
+	}
+	ca, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed reading the engine CA certificate: %v", err)
+	}
+
+	block, rest := pem.Decode(ca)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("the engine CA certificate isn't a PEM certificate")
+	}
+	// only the pinned certificate is trusted, anything appended to it isn't verified
+	if len(bytes.TrimSpace(rest)) > 0 {
+		return nil, fmt.Errorf("the engine CA certificate response holds more than the pinned certificate")
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed parsing the engine CA certificate: %v", err)
+	}
+	sum := sha256.Sum256(cert.Raw)
+	if actual := hex.EncodeToString(sum[:]); actual != pinned {
+		return nil, fmt.Errorf("the engine CA certificate fingerprint %s doesn't match the pinned fingerprint %s",
+			actual, pinned)
+	}
+	ca = pem.EncodeToMemory(block)
+	engineCAs.Store(key, ca)
+	return ca, nil
+}
+
+// forgetEngineCAs drops the cached engine CA certificates, so they are fetched again
+func forgetEngineCAs() {
+	engineCAs.Range(func(key, _ interface{}) bool {
+		engineCAs.Delete(key)
+		return true
+	})
+}
+
+// normalizeFingerprint returns the fingerprint as lower case hex without separators,
+// e.g "AB:CD:..." as printed by openssl becomes "abcd..."
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToLower(strings.NewReplacer(":", "", " ", "").Replace(fingerprint))
+}
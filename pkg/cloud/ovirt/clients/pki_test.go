@@ -0,0 +1,127 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newCACertificate returns a self-signed PEM CA certificate and its SHA-256 fingerprint
+func newCACertificate(t *testing.T, name string) ([]byte, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256(der)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), hex.EncodeToString(sum[:])
+}
+
+// opensslFingerprint formats the fingerprint as openssl prints it, e.g "AB:CD:..."
+func opensslFingerprint(fingerprint string) string {
+	var pairs []string
+	for i := 0; i < len(fingerprint); i += 2 {
+		pairs = append(pairs, strings.ToUpper(fingerprint[i:i+2]))
+	}
+	return strings.Join(pairs, ":")
+}
+
+func TestFetchEngineCA(t *testing.T) {
+	ca, fingerprint := newCACertificate(t, "engine CA")
+	other, _ := newCACertificate(t, "unpinned CA")
+
+	for _, tc := range []struct {
+		name        string
+		response    []byte
+		fingerprint string
+		wantErr     bool
+	}{
+		{
+			name:        "pinned certificate",
+			response:    ca,
+			fingerprint: fingerprint,
+		},
+		{
+			name:        "openssl fingerprint format",
+			response:    ca,
+			fingerprint: opensslFingerprint(fingerprint),
+		},
+		{
+			name:        "trailing new lines",
+			response:    append(append([]byte{}, ca...), "\n\n"...),
+			fingerprint: fingerprint,
+		},
+		{
+			name:        "fingerprint mismatch",
+			response:    other,
+			fingerprint: fingerprint,
+			wantErr:     true,
+		},
+		{
+			name:        "second unpinned certificate",
+			response:    append(append([]byte{}, ca...), other...),
+			fingerprint: fingerprint,
+			wantErr:     true,
+		},
+		{
+			name:        "bytes after the certificate",
+			response:    append(append([]byte{}, ca...), "garbage"...),
+			fingerprint: fingerprint,
+			wantErr:     true,
+		},
+		{
+			name:        "not PEM",
+			response:    []byte("<html></html>"),
+			fingerprint: fingerprint,
+			wantErr:     true,
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			engine := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Write(tc.response)
+			}))
+			defer engine.Close()
+			defer forgetEngineCAs()
+
+			got, err := fetchEngineCA(engine.URL+engineAPIPath, tc.fingerprint)
+			if (err != nil) != tc.wantErr {
+				t.Fatalf("fetchEngineCA() error = %v, want error %v", err, tc.wantErr)
+			}
+			if !tc.wantErr && !bytes.Equal(got, ca) {
+				t.Errorf("fetchEngineCA() = %q, want the pinned certificate only", got)
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidatePlacement checks the migration mode of the spec
+func ValidatePlacement(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	switch spec.MigrationMode {
+	case "", ovirtconfigv1.MigrationModeMigratable, ovirtconfigv1.MigrationModeUserMigratable, ovirtconfigv1.MigrationModePinned:
+	default:
+		return fmt.Errorf("migration mode must be %s, %s or %s, got %s", ovirtconfigv1.MigrationModeMigratable,
+			ovirtconfigv1.MigrationModeUserMigratable, ovirtconfigv1.MigrationModePinned, spec.MigrationMode)
+	}
+	if spec.MigrationMode != "" && spec.MigrationMode != ovirtconfigv1.MigrationModePinned && hostPinned(spec) {
+		return fmt.Errorf("a VM with pinned CPUs or NUMA nodes must have the %s migration mode, got %s",
+			ovirtconfigv1.MigrationModePinned, spec.MigrationMode)
+	}
+	return nil
+}
+
+// hostsSelected returns true if the spec selects the hosts the VM is placed on
+func hostsSelected(spec *ovirtconfigv1.OvirtMachineProviderSpec) bool {
+	return len(spec.PlacementHosts) > 0 || (spec.PreferredHosts != nil && len(spec.PreferredHosts.Tags) > 0)
+}
+
+// placementPolicy returns the placement policy of the VM, made of its placement hosts,
+// its preferred hosts and its migration mode, or nil if the VM has none
+func (is *InstanceService) placementPolicy(
+	machine *machinev1.Machine,
+	spec *ovirtconfigv1.OvirtMachineProviderSpec) (*ovirtsdk.VmPlacementPolicyBuilder, error) {
+
+	var hosts []*ovirtsdk.Host
+	if len(spec.PlacementHosts) > 0 {
+		placementHosts, err := is.getPlacementHosts(spec.ClusterId, spec.PlacementHosts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed resolving the placement hosts")
+		}
+		hosts = placementHosts
+	}
+	if spec.PreferredHosts != nil {
+		preferredHosts, err := is.getPreferredHosts(spec.ClusterId, spec.PreferredHosts)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed resolving the preferred hosts")
+		}
+		if len(preferredHosts) == 0 {
+			klog.Warningf("No host in cluster %s matches the preferred hosts of machine %s, skipping",
+				spec.ClusterId, machine.Name)
+		}
+		hosts = appendHosts(hosts, preferredHosts)
+	}
+
+	affinity := ovirtsdk.VMAFFINITY_MIGRATABLE
+	if spec.MigrationMode != "" {
+		affinity = ovirtsdk.VmAffinity(spec.MigrationMode)
+	}
+	if hostPinned(spec) {
+		// the engine pins the VM CPUs and NUMA nodes only for a VM pinned to its hosts
+		affinity = ovirtsdk.VMAFFINITY_PINNED
+		if len(hosts) == 0 {
+			return nil, fmt.Errorf("no host in cluster %s matches the placement or preferred hosts of machine %s "+
+				"to pin it to", spec.ClusterId, machine.Name)
+		}
+	}
+	if len(hosts) == 0 && spec.MigrationMode == "" {
+		return nil, nil
+	}
+	placementPolicy := ovirtsdk.NewVmPlacementPolicyBuilder().Affinity(affinity)
+	if len(hosts) > 0 {
+		placementPolicy.HostsOfAny(hosts...)
+	}
+	return placementPolicy, nil
+}
+
+// getPlacementHosts returns the hosts of the cluster with the given names, all of them must exist
+func (is *InstanceService) getPlacementHosts(cID string, names []string) ([]*ovirtsdk.Host, error) {
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return nil, err
+	}
+	var hosts []*ovirtsdk.Host
+	for _, name := range names {
+		res, err := is.Connection.SystemService().HostsService().
+			List().Search(scope.search("name=" + searchValue(name))).Send()
+		if err != nil {
+			return nil, err
+		}
+		var found *ovirtsdk.Host
+		for _, host := range res.MustHosts().Slice() {
+			cluster, ok := host.Cluster()
+			if ok && cluster.MustId() == cID && host.MustName() == name {
+				found = host
+			}
+		}
+		if found == nil {
+			return nil, fmt.Errorf("host %s not found in cluster %s", name, cID)
+		}
+		hosts = appendHosts(hosts, []*ovirtsdk.Host{ovirtsdk.NewHostBuilder().Id(found.MustId()).MustBuild()})
+	}
+	return hosts, nil
+}
+
+// appendHosts appends the hosts which aren't in the list yet
+func appendHosts(hosts []*ovirtsdk.Host, more []*ovirtsdk.Host) []*ovirtsdk.Host {
+	seen := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		seen[host.MustId()] = true
+	}
+	for _, host := range more {
+		if !seen[host.MustId()] {
+			seen[host.MustId()] = true
+			hosts = append(hosts, host)
+		}
+	}
+	return hosts
+}
@@ -0,0 +1,83 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// PrewarmTemplate copies the template disks of the spec to the storage domains the spec
+// places them on: the bootable disk to the storage domain of the OS disk, and the others
+// by their alias. The VMs created afterwards then find the template disks on their storage
+// domain, instead of the first VM on each domain waiting for a cross-domain clone. It
+// returns the aliases of the disks whose copy it started, and an InProgressError while
+// copies are running. Managed Block Storage domains are skipped, their disks are always
+// cloned.
+func (is *InstanceService) PrewarmTemplate(spec *ovirtconfigv1.OvirtMachineProviderSpec) ([]string, error) {
+	var osDiskSD *ovirtsdk.StorageDomain
+	if spec.OSDisk != nil {
+		sd, err := is.osDiskStorageDomain(spec.OSDisk)
+		if err != nil {
+			return nil, err
+		}
+		osDiskSD = sd
+	}
+	template, err := is.specTemplate(spec)
+	if err != nil {
+		return nil, err
+	}
+	res, err := is.Connection.SystemService().TemplatesService().
+		TemplateService(template.MustId()).DiskAttachmentsService().List().Follow("disk").Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching the disks of template %s", template.MustName())
+	}
+	placements := make(map[string]ovirtconfigv1.TemplateDisk, len(spec.TemplateDisks))
+	for _, disk := range spec.TemplateDisks {
+		placements[disk.Alias] = disk
+	}
+
+	var started []string
+	inProgress := false
+	for _, attachment := range res.MustAttachments().Slice() {
+		disk := attachment.MustDisk()
+		alias, _ := disk.Alias()
+		sd := osDiskSD
+		if !attachment.MustBootable() || sd == nil {
+			placement := placements[alias]
+			if sd, err = is.selectedStorageDomain(placement.StorageDomainId, placement.StorageDomainName); err != nil {
+				return nil, errors.Wrapf(err, "template disk %s", alias)
+			}
+		}
+		if sd == nil || isManagedBlockStorage(sd) || onStorageDomain(disk, sd.MustId()) {
+			continue
+		}
+		if status, _ := disk.Status(); status == ovirtsdk.DISKSTATUS_LOCKED {
+			// the disk is being copied to another storage domain
+			inProgress = true
+			continue
+		}
+		klog.Infof("Copying the disk %s of template %s to storage domain %s", alias, template.MustName(), sd.MustName())
+		_, err := is.Connection.SystemService().DisksService().DiskService(disk.MustId()).Copy().
+			StorageDomain(ovirtsdk.NewStorageDomainBuilder().Id(sd.MustId()).MustBuild()).Send()
+		if err != nil {
+			return started, errors.Wrapf(err, "failed copying the disk %s of template %s to storage domain %s",
+				alias, template.MustName(), sd.MustName())
+		}
+		started = append(started, alias)
+		inProgress = true
+	}
+	if inProgress {
+		return started, &InProgressError{Operation: fmt.Sprintf("copying the disks of template %s", template.MustName())}
+	}
+	return started, nil
+}
@@ -0,0 +1,123 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// clusterScope holds the names the engine search queries are scoped with, so an
+// engine user restricted to a single cluster doesn't issue system wide queries.
+type clusterScope struct {
+	clusterName    string
+	dataCenterID   string
+	dataCenterName string
+}
+
+// getClusterScope returns the scope of the cluster, fetching it once per instance service
+func (is *InstanceService) getClusterScope(cID string) (*clusterScope, error) {
+	if is.scope != nil && is.scopeClusterID == cID {
+		return is.scope, nil
+	}
+	res, err := is.Connection.SystemService().ClustersService().ClusterService(cID).Get().Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching cluster %s", cID)
+	}
+	cluster := res.MustCluster()
+	scope := &clusterScope{clusterName: cluster.MustName()}
+	if dc, ok := cluster.DataCenter(); ok {
+		dcRes, err := is.Connection.SystemService().DataCentersService().DataCenterService(dc.MustId()).Get().Send()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed fetching the data center of cluster %s", cluster.MustName())
+		}
+		scope.dataCenterID = dc.MustId()
+		scope.dataCenterName = dcRes.MustDataCenter().MustName()
+	}
+	is.scope, is.scopeClusterID = scope, cID
+	return scope, nil
+}
+
+// search returns the search query restricted to the cluster
+func (s *clusterScope) search(query string) string {
+	return query + " and cluster=" + searchValue(s.clusterName)
+}
+
+// searchDataCenter returns the search query restricted to the data center of the cluster
+func (s *clusterScope) searchDataCenter(query string) string {
+	if s.dataCenterName == "" {
+		return query
+	}
+	return query + " and datacenter=" + searchValue(s.dataCenterName)
+}
+
+// vmNameSearch returns the search query of the VMs named name, restricted to the
+// cluster with the ID cID if it is set
+func (is *InstanceService) vmNameSearch(name, cID string) (string, error) {
+	query := "name=" + searchValue(name)
+	if cID == "" {
+		return query, nil
+	}
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return "", err
+	}
+	return scope.search(query), nil
+}
+
+// VMNameSearch returns the search query of the VMs named name as GetVmByName searches
+// them, restricted to the cluster with the ID cID, or to no cluster if it is empty
+func VMNameSearch(connection *ovirtsdk.Connection, name, cID string) (string, error) {
+	is := &InstanceService{Connection: connection}
+	return is.vmNameSearch(name, cID)
+}
+
+// searchValue quotes a value of a search query, so names with spaces or search
+// keywords are matched as a whole
+func searchValue(value string) string {
+	return `"` + strings.ReplaceAll(value, `"`, `\"`) + `"`
+}
+
+// ValidateDataCenter checks that the cluster of the spec belongs to its expected data center
+func (is *InstanceService) ValidateDataCenter(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.DataCenterId == "" {
+		return nil
+	}
+	scope, err := is.getClusterScope(spec.ClusterId)
+	if err != nil {
+		return err
+	}
+	if scope.dataCenterID != spec.DataCenterId {
+		return invalidSpecf("cluster %s belongs to data center %s, not to data center %s",
+			scope.clusterName, scope.dataCenterName, spec.DataCenterId)
+	}
+	return nil
+}
+
+// checkAttached returns an error if the storage domain isn't attached to the data center of the cluster
+func (is *InstanceService) checkAttached(sd *ovirtsdk.StorageDomain, cID string) error {
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return err
+	}
+	if scope.dataCenterID == "" {
+		return nil
+	}
+	if dataCenters, ok := sd.DataCenters(); ok {
+		for _, dc := range dataCenters.Slice() {
+			if id, ok := dc.Id(); ok && id == scope.dataCenterID {
+				return nil
+			}
+		}
+	}
+	return invalidSpecf("storage domain %s isn't attached to data center %s of cluster %s",
+		sd.MustName(), scope.dataCenterName, scope.clusterName)
+}
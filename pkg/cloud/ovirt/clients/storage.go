@@ -0,0 +1,489 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ValidateStorage checks that the storage domains selected for the OS disk, the
+// template disks and the VM lease exist, are attached to the data center of the
+// cluster and support the requested options.
+func (is *InstanceService) ValidateStorage(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	aliases := make(map[string]bool, len(spec.TemplateDisks))
+	for _, disk := range spec.TemplateDisks {
+		if disk.Alias == "" || aliases[disk.Alias] {
+			return invalidSpecf("the template disks must have distinct aliases, got %q", disk.Alias)
+		}
+		aliases[disk.Alias] = true
+		sd, err := is.selectedStorageDomain(disk.StorageDomainId, disk.StorageDomainName)
+		if err != nil || sd == nil {
+			continue
+		}
+		if err := is.checkAttached(sd, spec.ClusterId); err != nil {
+			return errors.Wrapf(err, "template disk %s", disk.Alias)
+		}
+		if sd.MustType() != ovirtsdk.STORAGEDOMAINTYPE_DATA && sd.MustType() != ovirtsdk.STORAGEDOMAINTYPE_MANAGED_BLOCK_STORAGE {
+			return invalidSpecf("storage domain %s of template disk %s is of type %s and can't hold VM disks",
+				sd.MustName(), disk.Alias, sd.MustType())
+		}
+	}
+	if spec.Lease != nil {
+		if spec.Lease.StorageDomainId == "" {
+			return invalidSpecf("a VM lease requires a storage domain")
+		}
+		sd, err := is.getStorageDomain(spec.Lease.StorageDomainId)
+		if err != nil {
+			return err
+		}
+		if err := is.checkAttached(sd, spec.ClusterId); err != nil {
+			return err
+		}
+		// sanlock leases live on the domain metadata volumes, which only image based data domains have
+		if sd.MustType() != ovirtsdk.STORAGEDOMAINTYPE_DATA || isManagedBlockStorage(sd) {
+			return invalidSpecf("storage domain %s can't hold VM leases, a data storage domain is required", sd.MustName())
+		}
+	}
+	if spec.OSDisk == nil {
+		return nil
+	}
+	switch ovirtsdk.DiskInterface(spec.OSDisk.Interface) {
+	case "", ovirtsdk.DISKINTERFACE_VIRTIO, ovirtsdk.DISKINTERFACE_VIRTIO_SCSI, ovirtsdk.DISKINTERFACE_IDE:
+	default:
+		return invalidSpecf("unsupported OS disk interface %s, expected one of %s, %s or %s", spec.OSDisk.Interface,
+			ovirtsdk.DISKINTERFACE_VIRTIO, ovirtsdk.DISKINTERFACE_VIRTIO_SCSI, ovirtsdk.DISKINTERFACE_IDE)
+	}
+	// the engine passes discard requests only through VirtIO-SCSI and IDE disks
+	if spec.OSDisk.PassDiscard && spec.OSDisk.Interface == string(ovirtsdk.DISKINTERFACE_VIRTIO) {
+		return invalidSpecf("pass discard is not supported by the %s disk interface", spec.OSDisk.Interface)
+	}
+	sd, err := is.osDiskStorageDomain(spec.OSDisk)
+	if err != nil {
+		return err
+	}
+	if sd == nil {
+		if spec.OSDisk.Encrypted {
+			return invalidSpecf("an encrypted OS disk requires a storage domain")
+		}
+		return nil
+	}
+	if err := is.checkAttached(sd, spec.ClusterId); err != nil {
+		return err
+	}
+	if sd.MustType() != ovirtsdk.STORAGEDOMAINTYPE_DATA && sd.MustType() != ovirtsdk.STORAGEDOMAINTYPE_MANAGED_BLOCK_STORAGE {
+		return invalidSpecf("storage domain %s is of type %s and can't hold VM disks", sd.MustName(), sd.MustType())
+	}
+	if spec.OSDisk.Encrypted && !encryptedStorageDomain(sd) {
+		return invalidSpecf("storage domain %s doesn't support encrypted disks, "+
+			"a Managed Block Storage domain with an encryption driver option is required", sd.MustName())
+	}
+	return nil
+}
+
+// osDiskStorageDomain returns the storage domain selected for the OS disk by ID or by
+// name, or nil if none was selected
+func (is *InstanceService) osDiskStorageDomain(disk *ovirtconfigv1.Disk) (*ovirtsdk.StorageDomain, error) {
+	return is.selectedStorageDomain(disk.StorageDomainId, disk.StorageDomainName)
+}
+
+// selectedStorageDomain returns the storage domain selected by ID, or by name if the
+// ID is empty, or nil if none was selected
+func (is *InstanceService) selectedStorageDomain(id, name string) (*ovirtsdk.StorageDomain, error) {
+	if id != "" {
+		return is.getStorageDomain(id)
+	}
+	if name == "" {
+		return nil, nil
+	}
+	query := "name=" + searchValue(name)
+	if is.ClusterId != "" {
+		scope, err := is.getClusterScope(is.ClusterId)
+		if err != nil {
+			return nil, err
+		}
+		query = scope.searchDataCenter(query)
+	}
+	res, err := is.Connection.SystemService().StorageDomainsService().
+		List().Search(query).Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed searching storage domain %s", name)
+	}
+	for _, sd := range res.MustStorageDomains().Slice() {
+		if sd.MustName() == name {
+			return sd, nil
+		}
+	}
+	return nil, invalidSpecf("storage domain %s was not found", name)
+}
+
+func (is *InstanceService) getStorageDomain(id string) (*ovirtsdk.StorageDomain, error) {
+	res, err := is.Connection.SystemService().StorageDomainsService().
+		StorageDomainService(id).Get().Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching storage domain %s", id)
+	}
+	return res.MustStorageDomain(), nil
+}
+
+// isManagedBlockStorage returns true if the storage domain is a Managed Block Storage (cinderlib) domain
+func isManagedBlockStorage(sd *ovirtsdk.StorageDomain) bool {
+	if sd.MustType() == ovirtsdk.STORAGEDOMAINTYPE_MANAGED_BLOCK_STORAGE {
+		return true
+	}
+	storage, ok := sd.Storage()
+	return ok && storage.MustType() == ovirtsdk.STORAGETYPE_MANAGED_BLOCK_STORAGE
+}
+
+// isManagedBlockDisk returns true if the disk is stored on a Managed Block Storage domain
+func isManagedBlockDisk(disk *ovirtsdk.Disk) bool {
+	storageType, ok := disk.StorageType()
+	return ok && storageType == ovirtsdk.DISKSTORAGETYPE_MANAGED_BLOCK_STORAGE
+}
+
+// encryptedStorageDomain returns true if the storage domain is a Managed Block Storage
+// domain whose driver encrypts the volumes
+func encryptedStorageDomain(sd *ovirtsdk.StorageDomain) bool {
+	if !isManagedBlockStorage(sd) {
+		return false
+	}
+	storage, ok := sd.Storage()
+	if !ok {
+		return false
+	}
+	options, ok := storage.DriverOptions()
+	if !ok {
+		return false
+	}
+	for _, option := range options.Slice() {
+		name, _ := option.Name()
+		value, _ := option.Value()
+		if !strings.Contains(strings.ToLower(name), "encrypt") {
+			continue
+		}
+		if enabled, err := strconv.ParseBool(value); err == nil && enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// getTemplate returns the version of the template named name in the data center of the
+// cluster: the version number, the newest version for "latest", or the base version if
+// the version is empty.
+func (is *InstanceService) getTemplate(name string, version string, cID string) (*ovirtsdk.Template, error) {
+	versionNumber := int64(1)
+	if version != "" && version != ovirtconfigv1.TemplateVersionLatest {
+		n, err := strconv.ParseInt(version, 10, 64)
+		if err != nil || n < 1 {
+			return nil, invalidSpecf("invalid version %q of template %s", version, name)
+		}
+		versionNumber = n
+	}
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := is.Connection.SystemService().TemplatesService().
+		List().Search(scope.searchDataCenter("name=" + searchValue(name))).Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching template %s", name)
+	}
+	var found *ovirtsdk.Template
+	foundNumber := int64(0)
+	for _, t := range res.MustTemplates().Slice() {
+		if t.MustName() != name {
+			continue
+		}
+		number := int64(1)
+		if v, ok := t.Version(); ok {
+			number = v.MustVersionNumber()
+		}
+		if version == ovirtconfigv1.TemplateVersionLatest {
+			if number > foundNumber {
+				found, foundNumber = t, number
+			}
+			continue
+		}
+		if number == versionNumber {
+			found = t
+		}
+	}
+	if found == nil {
+		if version != "" {
+			return nil, invalidSpecf("version %s of template %s was not found", version, name)
+		}
+		return nil, invalidSpecf("template %s was not found", name)
+	}
+	return found, nil
+}
+
+// templateDiskAttachments returns the disk attachments placing the template disks on
+// the storage domains and disk profiles selected in the spec: the bootable disk on the
+// storage domain of the OS disk, and the others by their alias. A disk without a
+// placement, or already on its selected storage domain without a disk profile, isn't
+// returned, so it stays a thin copy if no disk is cloned.
+func (is *InstanceService) templateDiskAttachments(spec *ovirtconfigv1.OvirtMachineProviderSpec) ([]*ovirtsdk.DiskAttachment, error) {
+	var osDiskSD *ovirtsdk.StorageDomain
+	if spec.OSDisk != nil {
+		sd, err := is.osDiskStorageDomain(spec.OSDisk)
+		if err != nil {
+			return nil, err
+		}
+		osDiskSD = sd
+	}
+	if osDiskSD == nil && len(spec.TemplateDisks) == 0 {
+		return nil, nil
+	}
+	template, err := is.specTemplate(spec)
+	if err != nil {
+		return nil, err
+	}
+	res, err := is.Connection.SystemService().TemplatesService().
+		TemplateService(template.MustId()).DiskAttachmentsService().List().Follow("disk").Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching the disks of template %s", template.MustName())
+	}
+
+	placements := make(map[string]ovirtconfigv1.TemplateDisk, len(spec.TemplateDisks))
+	for _, disk := range spec.TemplateDisks {
+		placements[disk.Alias] = disk
+	}
+	var attachments []*ovirtsdk.DiskAttachment
+	bootable := false
+	for _, attachment := range res.MustAttachments().Slice() {
+		disk := attachment.MustDisk()
+		alias, _ := disk.Alias()
+		placement := placements[alias]
+		delete(placements, alias)
+
+		sd := osDiskSD
+		if attachment.MustBootable() {
+			bootable = true
+		}
+		if !attachment.MustBootable() || sd == nil {
+			if sd, err = is.selectedStorageDomain(placement.StorageDomainId, placement.StorageDomainName); err != nil {
+				return nil, errors.Wrapf(err, "template disk %s", alias)
+			}
+		}
+		if sd == nil && placement.DiskProfileName == "" {
+			// nothing to place, e.g the template disk only sets wipe after delete
+			continue
+		}
+		if sd != nil && onStorageDomain(disk, sd.MustId()) && placement.DiskProfileName == "" {
+			klog.V(5).Infof("The disk %s of template %s is on storage domain %s, skipping the clone",
+				alias, template.MustName(), sd.MustName())
+			continue
+		}
+
+		builder := ovirtsdk.NewDiskBuilder().Id(disk.MustId())
+		sdID := ""
+		if sd != nil {
+			sdID = sd.MustId()
+			builder.StorageDomainsOfAny(ovirtsdk.NewStorageDomainBuilder().Id(sdID).MustBuild())
+			if isManagedBlockStorage(sd) {
+				// Managed Block Storage volumes are always raw and fully allocated, the
+				// engine refuses cloning a qcow or sparse template disk onto them.
+				builder.Format(ovirtsdk.DISKFORMAT_RAW).Sparse(false)
+			}
+		} else if domains, ok := disk.StorageDomains(); ok && len(domains.Slice()) > 0 {
+			sdID = domains.Slice()[0].MustId()
+		}
+		if placement.DiskProfileName != "" {
+			profileID, err := is.diskProfileID(sdID, placement.DiskProfileName)
+			if err != nil {
+				return nil, errors.Wrapf(err, "template disk %s", alias)
+			}
+			builder.DiskProfile(ovirtsdk.NewDiskProfileBuilder().Id(profileID).MustBuild())
+		}
+		attachments = append(attachments, ovirtsdk.NewDiskAttachmentBuilder().DiskBuilder(builder).MustBuild())
+	}
+	if osDiskSD != nil && !bootable {
+		return nil, invalidSpecf("template %s doesn't have a bootable disk", template.MustName())
+	}
+	for _, disk := range spec.TemplateDisks {
+		if _, missing := placements[disk.Alias]; missing {
+			return nil, invalidSpecf("template %s doesn't have a disk with alias %s", template.MustName(), disk.Alias)
+		}
+	}
+	return attachments, nil
+}
+
+// diskProfileID returns the ID of the disk profile of the storage domain with the given name
+func (is *InstanceService) diskProfileID(sdID, name string) (string, error) {
+	if sdID == "" {
+		return "", invalidSpecf("disk profile %s requires a storage domain", name)
+	}
+	res, err := is.Connection.SystemService().StorageDomainsService().StorageDomainService(sdID).
+		DiskProfilesService().List().Send()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed fetching the disk profiles of storage domain %s", sdID)
+	}
+	for _, profile := range res.MustProfiles().Slice() {
+		if profile.MustName() == name {
+			return profile.MustId(), nil
+		}
+	}
+	return "", invalidSpecf("disk profile %s was not found in storage domain %s", name, sdID)
+}
+
+// OSDiskStorageEstimate returns the storage domain the OS disk of a machine is created
+// on, and the provisioned size of the disk in bytes: the spec size if bigger than the
+// template disk size, which is never shrunk.
+func (is *InstanceService) OSDiskStorageEstimate(spec *ovirtconfigv1.OvirtMachineProviderSpec) (*ovirtsdk.StorageDomain, int64, error) {
+	template, err := is.specTemplate(spec)
+	if err != nil {
+		return nil, 0, err
+	}
+	res, err := is.Connection.SystemService().TemplatesService().
+		TemplateService(template.MustId()).DiskAttachmentsService().List().Follow("disk").Send()
+	if err != nil {
+		return nil, 0, errors.Wrapf(err, "failed fetching the disks of template %s", template.MustName())
+	}
+	for _, attachment := range res.MustAttachments().Slice() {
+		if !attachment.MustBootable() {
+			continue
+		}
+		disk := attachment.MustDisk()
+		size := disk.MustProvisionedSize()
+		var sd *ovirtsdk.StorageDomain
+		if spec.OSDisk != nil {
+			if specSize := spec.OSDisk.SizeGB * int64(math.Pow(2, 30)); specSize > size {
+				size = specSize
+			}
+			if sd, err = is.osDiskStorageDomain(spec.OSDisk); err != nil {
+				return nil, 0, err
+			}
+		}
+		if sd == nil {
+			domains, ok := disk.StorageDomains()
+			if !ok || len(domains.Slice()) == 0 {
+				return nil, 0, fmt.Errorf("the disk of template %s has no storage domain", template.MustName())
+			}
+			if sd, err = is.getStorageDomain(domains.Slice()[0].MustId()); err != nil {
+				return nil, 0, err
+			}
+		}
+		return sd, size, nil
+	}
+	return nil, 0, fmt.Errorf("template %s doesn't have a bootable disk", template.MustName())
+}
+
+// onStorageDomain returns true if the disk is stored on the storage domain
+func onStorageDomain(disk *ovirtsdk.Disk, storageDomainID string) bool {
+	domains, ok := disk.StorageDomains()
+	if !ok {
+		return false
+	}
+	for _, sd := range domains.Slice() {
+		if id, ok := sd.Id(); ok && id == storageDomainID {
+			return true
+		}
+	}
+	return false
+}
+
+// handleDiskAttachment attaches the bootable disk of the VM with the interface and
+// the pass discard of the spec, if they differ from the ones inherited from the template.
+func (is *InstanceService) handleDiskAttachment(vmService *ovirtsdk.VmService, vm *ovirtsdk.Vm, disk *ovirtconfigv1.Disk) error {
+	if disk.Interface == "" && !disk.PassDiscard {
+		return nil
+	}
+	res, err := vmService.DiskAttachmentsService().List().Send()
+	if err != nil {
+		return err
+	}
+	for _, attachment := range res.MustAttachments().Slice() {
+		if !attachment.MustBootable() {
+			continue
+		}
+		update := ovirtsdk.NewDiskAttachmentBuilder()
+		changed := false
+		if current, ok := attachment.Interface(); disk.Interface != "" && (!ok || string(current) != disk.Interface) {
+			update.Interface(ovirtsdk.DiskInterface(disk.Interface))
+			changed = true
+		}
+		if current, ok := attachment.PassDiscard(); disk.PassDiscard && (!ok || !current) {
+			update.PassDiscard(true)
+			changed = true
+		}
+		if !changed {
+			return nil
+		}
+		klog.Infof("Updating the OS disk attachment of VM %s, interface: %q, pass discard: %v",
+			vm.MustName(), disk.Interface, disk.PassDiscard)
+		_, err := vmService.DiskAttachmentsService().
+			AttachmentService(attachment.MustId()).
+			Update().
+			DiskAttachment(update.MustBuild()).
+			Send()
+		if err != nil {
+			return errors.Wrap(err, "failed updating the OS disk attachment")
+		}
+		return nil
+	}
+	return fmt.Errorf("the VM %s doesn't have a bootable disk", vm.MustName())
+}
+
+// handleWipeAfterDelete marks the disks of the VM requesting it to be wiped when they are
+// removed, the bootable disk by the OS disk and the other disks by their template disk alias.
+func (is *InstanceService) handleWipeAfterDelete(vmService *ovirtsdk.VmService, vm *ovirtsdk.Vm, spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	osDiskWipe := spec.OSDisk != nil && spec.OSDisk.WipeAfterDelete
+	wipe := make(map[string]bool, len(spec.TemplateDisks))
+	for _, disk := range spec.TemplateDisks {
+		if disk.WipeAfterDelete {
+			wipe[disk.Alias] = true
+		}
+	}
+	if !osDiskWipe && len(wipe) == 0 {
+		return nil
+	}
+	res, err := vmService.DiskAttachmentsService().List().Follow("disk").Send()
+	if err != nil {
+		return err
+	}
+	bootable := false
+	for _, attachment := range res.MustAttachments().Slice() {
+		disk := attachment.MustDisk()
+		alias, _ := disk.Alias()
+		if attachment.MustBootable() {
+			bootable = true
+			if !osDiskWipe && !wipe[alias] {
+				continue
+			}
+		} else if !wipe[alias] {
+			continue
+		}
+		if wiped, ok := disk.WipeAfterDelete(); ok && wiped {
+			continue
+		}
+		klog.Infof("Setting wipe after delete on disk %s of VM %s", alias, vm.MustName())
+		_, err := vmService.DiskAttachmentsService().
+			AttachmentService(attachment.MustId()).
+			Update().
+			DiskAttachment(ovirtsdk.NewDiskAttachmentBuilder().
+				DiskBuilder(ovirtsdk.NewDiskBuilder().
+					WipeAfterDelete(true)).
+				MustBuild()).
+			Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed setting wipe after delete on disk %s", alias)
+		}
+	}
+	if osDiskWipe && !bootable {
+		return fmt.Errorf("the VM %s doesn't have a bootable disk", vm.MustName())
+	}
+	return nil
+}
@@ -0,0 +1,110 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+)
+
+// destroyVMTimeout is the time to wait for a VM of a destroyed cluster to power off
+const destroyVMTimeout = 5 * time.Minute
+
+// DestroyCluster removes the engine objects left by the OpenShift cluster with the ID:
+// the VMs tagged with the cluster ID, the affinity groups the provider created for the
+// cluster, and the cluster tag. The affinity groups created otherwise, e.g by the
+// installer or by hand, carry no marker and are kept even if they held the VMs of the
+// cluster, they have to be removed by whoever created them. The objects are looked up
+// by the cluster ID on every call, so it can be called again after a failure, e.g from
+// openshift-install destroy, and the objects already removed are skipped.
+func DestroyCluster(connection *ovirtsdk.Connection, clusterID string) error {
+	if clusterID == "" {
+		return fmt.Errorf("the cluster ID must be set")
+	}
+	res, err := connection.SystemService().VmsService().List().Search("tag=" + searchValue(clusterID)).Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed listing the VMs of cluster %s", clusterID)
+	}
+	for _, vm := range res.MustVms().Slice() {
+		if err := destroyVM(connection, vm); err != nil {
+			return err
+		}
+	}
+
+	if err := destroyAffinityGroups(connection, clusterID); err != nil {
+		return err
+	}
+
+	tags, err := connection.SystemService().TagsService().List().Send()
+	if err != nil {
+		return errors.Wrap(err, "failed listing the tags")
+	}
+	for _, tag := range tags.MustTags().Slice() {
+		if name, ok := tag.Name(); !ok || name != clusterID {
+			continue
+		}
+		klog.Infof("Removing tag %s", clusterID)
+		if _, err := connection.SystemService().TagsService().TagService(tag.MustId()).Remove().Send(); err != nil {
+			return errors.Wrapf(err, "failed removing tag %s", clusterID)
+		}
+	}
+	return nil
+}
+
+// destroyAffinityGroups removes the affinity groups of every oVirt cluster which the
+// provider created for the OpenShift cluster with the ID
+func destroyAffinityGroups(connection *ovirtsdk.Connection, clusterID string) error {
+	clusters, err := connection.SystemService().ClustersService().List().Send()
+	if err != nil {
+		return errors.Wrap(err, "failed listing the clusters")
+	}
+	for _, cluster := range clusters.MustClusters().Slice() {
+		agService := connection.SystemService().ClustersService().
+			ClusterService(cluster.MustId()).AffinityGroupsService()
+		res, err := agService.List().Send()
+		if err != nil {
+			return errors.Wrapf(err, "failed listing the affinity groups of cluster %s", cluster.MustName())
+		}
+		for _, ag := range res.MustGroups().Slice() {
+			if description, _ := ag.Description(); description != affinityGroupDescription(clusterID) {
+				continue
+			}
+			klog.Infof("Removing affinity group %s", ag.MustName())
+			if _, err := agService.GroupService(ag.MustId()).Remove().Send(); err != nil {
+				return errors.Wrapf(err, "failed removing affinity group %s", ag.MustName())
+			}
+		}
+	}
+	return nil
+}
+
+// destroyVM powers off and removes the VM, without waiting for its disks to be removed
+func destroyVM(connection *ovirtsdk.Connection, vm *ovirtsdk.Vm) error {
+	id := vm.MustId()
+	vmService := connection.SystemService().VmsService().VmService(id)
+	if vm.MustStatus() != ovirtsdk.VMSTATUS_DOWN {
+		klog.Infof("Stopping VM %s", vm.MustName())
+		if _, err := vmService.Stop().Send(); err != nil {
+			return errors.Wrapf(err, "failed stopping VM %s", vm.MustName())
+		}
+		if err := connection.WaitForVM(id, ovirtsdk.VMSTATUS_DOWN, destroyVMTimeout); err != nil {
+			return errors.Wrapf(err, "failed waiting for VM %s to stop", vm.MustName())
+		}
+	}
+	if err := clearDeleteProtection(vmService, vm); err != nil {
+		return err
+	}
+	klog.Infof("Removing VM %s", vm.MustName())
+	if _, err := vmService.Remove().Send(); err != nil {
+		return errors.Wrapf(err, "failed removing VM %s", vm.MustName())
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/pkg/errors"
+	"k8s.io/klog"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// ResolveTemplate sets the template name of the spec to the newest template carrying
+// the template tag of the spec, if the spec selects its template by tag
+func (is *InstanceService) ResolveTemplate(spec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+	if spec.TemplateID != "" || spec.TemplateName != "" || spec.TemplateTag == "" {
+		return nil
+	}
+	template, err := is.getTaggedTemplate(spec.TemplateTag, spec.ClusterId)
+	if err != nil {
+		return err
+	}
+	klog.Infof("Resolved the template tagged %s to template %s", spec.TemplateTag, template.MustName())
+	spec.TemplateName = template.MustName()
+	is.TemplateName = spec.TemplateName
+	return nil
+}
+
+// specTemplate returns the template of the spec, by its ID if set, else by its name and version
+func (is *InstanceService) specTemplate(spec *ovirtconfigv1.OvirtMachineProviderSpec) (*ovirtsdk.Template, error) {
+	if spec.TemplateID == "" {
+		return is.getTemplate(spec.TemplateName, spec.TemplateVersion, spec.ClusterId)
+	}
+	res, err := is.Connection.SystemService().TemplatesService().TemplateService(spec.TemplateID).Get().Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching template %s", spec.TemplateID)
+	}
+	return res.MustTemplate(), nil
+}
+
+// getTaggedTemplate returns the newest base template carrying the tag in the data center
+// of the cluster. The sub versions are skipped, as the VMs are created from the base
+// version of the template name.
+func (is *InstanceService) getTaggedTemplate(tag string, cID string) (*ovirtsdk.Template, error) {
+	scope, err := is.getClusterScope(cID)
+	if err != nil {
+		return nil, err
+	}
+	res, err := is.Connection.SystemService().TemplatesService().
+		List().Search(scope.searchDataCenter("tag=" + searchValue(tag))).Send()
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed fetching the templates tagged %s", tag)
+	}
+	var newest *ovirtsdk.Template
+	for _, t := range res.MustTemplates().Slice() {
+		if version, ok := t.Version(); ok && version.MustVersionNumber() != 1 {
+			continue
+		}
+		if newest == nil || t.MustCreationTime().After(newest.MustCreationTime()) {
+			newest = t
+		}
+	}
+	if newest == nil {
+		return nil, invalidSpecf("no template is tagged %s", tag)
+	}
+	return newest, nil
+}
@@ -0,0 +1,134 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package clients
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	ovirtsdk "github.com/ovirt/go-ovirt"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// vnicProfileID returns the ID of the vNIC profile of the network interface, looking the
+// profile up by name among the profiles of the networks of the cluster if it has no ID.
+// The name may be qualified by the network, as "network/profile".
+func (is *InstanceService) vnicProfileID(nic *ovirtconfigv1.NetworkInterface, cID string) (string, error) {
+	if nic.VNICProfileID != "" {
+		return nic.VNICProfileID, nil
+	}
+	networkName, profileName := "", nic.VNICProfileName
+	if i := strings.Index(profileName, "/"); i >= 0 {
+		networkName, profileName = profileName[:i], profileName[i+1:]
+	}
+
+	networks, err := is.Connection.SystemService().ClustersService().ClusterService(cID).
+		NetworksService().List().Send()
+	if err != nil {
+		return "", errors.Wrapf(err, "failed fetching the networks of cluster %s", cID)
+	}
+	var found []string
+	for _, network := range networks.MustNetworks().Slice() {
+		if networkName != "" && network.MustName() != networkName {
+			continue
+		}
+		profiles, err := is.Connection.SystemService().NetworksService().NetworkService(network.MustId()).
+			VnicProfilesService().List().Send()
+		if err != nil {
+			return "", errors.Wrapf(err, "failed fetching the vNIC profiles of network %s", network.MustName())
+		}
+		for _, profile := range profiles.MustProfiles().Slice() {
+			if profile.MustName() == profileName {
+				found = append(found, profile.MustId())
+			}
+		}
+	}
+	switch len(found) {
+	case 0:
+		return "", invalidSpecf("vNIC profile %s was not found in cluster %s", nic.VNICProfileName, cID)
+	case 1:
+		return found[0], nil
+	}
+	return "", invalidSpecf("vNIC profile %s is used by several networks of cluster %s, qualify it as network/profile",
+		nic.VNICProfileName, cID)
+}
+
+// checkNetworkFilter checks that the vNIC profile has the network filter the network
+// interface requires, if any
+func (is *InstanceService) checkNetworkFilter(nic *ovirtconfigv1.NetworkInterface, profileID string) error {
+	if nic.NetworkFilter == "" {
+		return nil
+	}
+	res, err := is.Connection.SystemService().VnicProfilesService().ProfileService(profileID).Get().Send()
+	if err != nil {
+		return errors.Wrapf(err, "failed fetching vNIC profile %s", profileID)
+	}
+	filterName := ovirtconfigv1.NetworkFilterNone
+	if filter, ok := res.MustProfile().NetworkFilter(); ok {
+		filters, err := is.Connection.SystemService().NetworkFiltersService().List().Send()
+		if err != nil {
+			return errors.Wrap(err, "failed fetching the network filters")
+		}
+		for _, f := range filters.MustFilters().Slice() {
+			if f.MustId() == filter.MustId() {
+				filterName = f.MustName()
+			}
+		}
+	}
+	if filterName != nic.NetworkFilter {
+		return fmt.Errorf("vNIC profile %s has the network filter %s, the network interface requires %s, "+
+			"select a vNIC profile with the required filter", res.MustProfile().MustName(), filterName, nic.NetworkFilter)
+	}
+	return nil
+}
+
+// reconcileNetworkFilterParameters sets the network filter parameters of the network
+// interface: the existing parameters are listed, the missing ones are added and the
+// ones of another value are updated. The parameters which aren't in the spec are kept.
+func reconcileNetworkFilterParameters(nicService *ovirtsdk.VmNicService, parameters map[string]string) error {
+	parametersService := nicService.NetworkFilterParametersService()
+	res, err := parametersService.List().Send()
+	if err != nil {
+		return err
+	}
+	existing := make(map[string]*ovirtsdk.NetworkFilterParameter)
+	if list, ok := res.Parameters(); ok {
+		for _, parameter := range list.Slice() {
+			if name, ok := parameter.Name(); ok {
+				existing[name] = parameter
+			}
+		}
+	}
+
+	names := make([]string, 0, len(parameters))
+	for name := range parameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value := parameters[name]
+		parameter := ovirtsdk.NewNetworkFilterParameterBuilder().Name(name).Value(value).MustBuild()
+		current, ok := existing[name]
+		if !ok {
+			if _, err := parametersService.Add().Parameter(parameter).Send(); err != nil {
+				return err
+			}
+			continue
+		}
+		if currentValue, _ := current.Value(); currentValue == value {
+			continue
+		}
+		_, err := parametersService.ParameterService(current.MustId()).Update().Parameter(parameter).Send()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+package defaultscontroller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/klog/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+)
+
+// RevalidationInterval is the interval the defaults are validated again at, the engine
+// objects they reference may be removed or renamed meanwhile
+const RevalidationInterval = 10 * time.Minute
+
+var _ reconcile.Reconciler = &defaultsReconciler{}
+
+// defaultsReconciler validates the OvirtMachineProviderDefaults against the engine
+// and reports the result in their status
+type defaultsReconciler struct {
+	log         logr.Logger
+	client      client.Client
+	connections *clients.ConnectionPool
+	// credentialsSecret is the secret of the engine credentials the defaults are validated with
+	credentialsSecret types.NamespacedName
+}
+
+// +kubebuilder:rbac:groups=ovirtproviderconfig.machine.openshift.io,resources=ovirtmachineproviderdefaults,verbs=get;list;watch
+// +kubebuilder:rbac:groups=ovirtproviderconfig.machine.openshift.io,resources=ovirtmachineproviderdefaults/status,verbs=get;update;patch
+
+func (r *defaultsReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	r.log.Info("Reconciling", "OvirtMachineProviderDefaults", request.Name)
+
+	defaults := &ovirtconfigv1.OvirtMachineProviderDefaults{}
+	if err := r.client.Get(ctx, request.NamespacedName, defaults); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("error getting defaults %s: %v", request.Name, err)
+	}
+	connection, err := r.connections.Get(r.credentialsSecret.Namespace, r.credentialsSecret.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to create connection to oVirt API: %v", err)
+	}
+	condition := ovirtconfigv1.OvirtMachineProviderDefaultsCondition{
+		Type:    ovirtconfigv1.DefaultsValid,
+		Status:  corev1.ConditionTrue,
+		Reason:  "Valid",
+		Message: "The engine objects referenced by the defaults exist",
+	}
+	err = clients.ValidateDefaults(connection, &defaults.Spec)
+	r.connections.Release(connection)
+	if err != nil {
+		if !clients.IsInvalidSpec(err) {
+			// the engine couldn't be checked, the defaults are validated again with a backoff
+			return reconcile.Result{}, fmt.Errorf("failed validating defaults %s: %v", request.Name, err)
+		}
+		condition.Status = corev1.ConditionFalse
+		condition.Reason = "InvalidReference"
+		condition.Message = err.Error()
+	}
+
+	original := defaults.Status.DeepCopy()
+	defaults.Status.ObservedGeneration = defaults.Generation
+	defaults.Status.Conditions = setCondition(defaults.Status.Conditions, condition)
+	if equality.Semantic.DeepEqual(original, &defaults.Status) {
+		return reconcile.Result{RequeueAfter: RevalidationInterval}, nil
+	}
+	if err := r.client.Status().Update(ctx, defaults); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed updating the status of defaults %s: %v", request.Name, err)
+	}
+	return reconcile.Result{RequeueAfter: RevalidationInterval}, nil
+}
+
+// setCondition sets the condition in the conditions, keeping its transition time if its status didn't change
+func setCondition(
+	conditions []ovirtconfigv1.OvirtMachineProviderDefaultsCondition,
+	condition ovirtconfigv1.OvirtMachineProviderDefaultsCondition) []ovirtconfigv1.OvirtMachineProviderDefaultsCondition {
+
+	existing := findCondition(conditions, condition.Type)
+	if existing == nil {
+		condition.LastTransitionTime = metav1.Now()
+		return append(conditions, condition)
+	}
+	if existing.Status != condition.Status {
+		existing.LastTransitionTime = metav1.Now()
+	}
+	existing.Status = condition.Status
+	existing.Reason = condition.Reason
+	existing.Message = condition.Message
+	return conditions
+}
+
+func findCondition(
+	conditions []ovirtconfigv1.OvirtMachineProviderDefaultsCondition,
+	conditionType ovirtconfigv1.OvirtMachineProviderDefaultsConditionType) *ovirtconfigv1.OvirtMachineProviderDefaultsCondition {
+
+	for i := range conditions {
+		if conditions[i].Type == conditionType {
+			return &conditions[i]
+		}
+	}
+	return nil
+}
+
+// Add registers the controller, which validates the defaults with the engine credentials
+// of the secret, over the connections of the pool shared with the machine actuator
+func Add(mgr manager.Manager, opts manager.Options, credentialsSecret types.NamespacedName, connections *clients.ConnectionPool) error {
+	reconciler := NewDefaultsReconciler(mgr, credentialsSecret, connections)
+
+	c, err := controller.New("defaults-controller", mgr, controller.Options{Reconciler: reconciler})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &ovirtconfigv1.OvirtMachineProviderDefaults{}}, &handler.EnqueueRequestForObject{})
+}
+
+func NewDefaultsReconciler(
+	mgr manager.Manager,
+	credentialsSecret types.NamespacedName,
+	connections *clients.ConnectionPool) *defaultsReconciler {
+
+	log.SetLogger(klogr.New())
+	return &defaultsReconciler{
+		log:               log.Log.WithName("controllers").WithName("defaults-reconciler"),
+		client:            mgr.GetClient(),
+		connections:       connections,
+		credentialsSecret: credentialsSecret,
+	}
+}
@@ -9,16 +9,22 @@ import (
 	"context"
 	"fmt"
 	"k8s.io/client-go/rest"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	apierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
-	"github.com/openshift/machine-api-operator/pkg/generated/clientset/versioned/typed/machine/v1beta1"
-	"github.com/openshift/machine-api-operator/pkg/util"
 
 	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/tools/record"
 	"k8s.io/klog"
@@ -27,47 +33,145 @@ import (
 	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/metrics"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/notifier"
 	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/prometheus/client_golang/prometheus"
 
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	ctrl "sigs.k8s.io/controller-runtime"
 )
 
 const (
-	TimeoutInstanceCreate       = 5 * time.Minute
 	RetryIntervalInstanceStatus = 10 * time.Second
+	// TimeoutInstanceCreate is the time to wait for the template disks of a new VM to be cloned.
+	//
+	// Deprecated: use clients.DefaultCloneTimeout, the clone timeout is set per actuator by
+	// ActuatorParams.CreateTimeout.
+	TimeoutInstanceCreate = clients.DefaultCloneTimeout
+	// RetryIntervalVMDeletion is the time to wait before retrying a failed VM removal
+	RetryIntervalVMDeletion = time.Minute
+	// RetryIntervalTransientState is the time to wait for a VM paused, suspended or
+	// not responding, which takes longer than a VM powering up to settle
+	RetryIntervalTransientState = time.Minute
+	// maxCPUShares is the highest CPU shares value the engine accepts
+	maxCPUShares                = 262144
 	InstanceStatusAnnotationKey = "machine.openshift.io/instance-state"
+	// FailedMachinesAnnotation is set on a MachineSet with the number of its machines failing
+	// per error reason, e.g "CreateError=3", so failures can be handled at the pool level
+	FailedMachinesAnnotation = "ovirt.machine.openshift.io/failed-machines"
+	// ForceDeleteAnnotation makes the deletion of a machine power off and remove its VM
+	// right away, skipping the graceful shutdown and the evacuation, for emergency remediation
+	ForceDeleteAnnotation = "ovirt.machine.openshift.io/force-delete"
+	// CPUAnnotation and MemoryMBAnnotation are set on a MachineSet with the capacity of the
+	// instance type of its machines, e.g for the autoscaler to scale the MachineSet from zero
+	CPUAnnotation      = "machine.openshift.io/vCPU"
+	MemoryMBAnnotation = "machine.openshift.io/memoryMb"
+	machineSetLabel    = "machine.openshift.io/cluster-api-machineset"
+	// machinePhaseFailed is the phase of the machines the machine controller doesn't reconcile anymore
+	machinePhaseFailed = "Failed"
+	// ErrorUpdateInterval is the minimal interval between two updates of a machine status
+	// with the same error
+	ErrorUpdateInterval = time.Minute
+	// AllocationRefreshInterval is the interval the provisioned disk size of a machine is
+	// fetched again at, when neither the machine spec nor its VM changed
+	AllocationRefreshInterval = 10 * time.Minute
+	// ClusterCPURefreshInterval is the interval the CPU type of an oVirt cluster is fetched again at
+	ClusterCPURefreshInterval = 10 * time.Minute
 )
 
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machines;machines/status,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machinesets,verbs=get;list;watch;update;patch
+// +kubebuilder:rbac:groups="",resources=secrets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+// +kubebuilder:rbac:groups=config.openshift.io,resources=infrastructures,verbs=get;list;watch
+
+// OvirtActuator is shared by the concurrent machine reconciles, it holds no
+// per-reconcile state. Connections are leased from the pool for a single call
+// and the instance services are built per call.
 type OvirtActuator struct {
-	params         ovirt.ActuatorParams
-	scheme         *runtime.Scheme
-	client         client.Client
-	KubeClient     *kubernetes.Clientset
-	machinesClient v1beta1.MachineV1beta1Interface
-	EventRecorder  record.EventRecorder
-	ovirtApi       *ovirtsdk.Connection
-	OSClient       osclientset.Interface
+	params        ovirt.ActuatorParams
+	scheme        *runtime.Scheme
+	client        client.Client
+	KubeClient    *kubernetes.Clientset
+	EventRecorder record.EventRecorder
+	connections   *clients.ConnectionPool
+	OSClient      osclientset.Interface
+	// errorUpdates holds the last errorUpdate per machine UID
+	errorUpdates sync.Map
+	// vmSnapshots holds the vmSnapshot taken by Exists per machine UID
+	vmSnapshots sync.Map
+	// transientStates holds the last transient VM status reported per machine UID
+	transientStates sync.Map
+	// allocations holds the last allocation reported per machine UID
+	allocations sync.Map
+	// clusterCPUs holds the fetched clusterCPU per oVirt cluster ID
+	clusterCPUs sync.Map
+	notifier    *notifier.Notifier
+	permissions *clients.PermissionsChecker
 }
 
 
 
 func NewActuator(params ovirt.ActuatorParams) (*OvirtActuator, error) {
-	config := ctrl.GetConfigOrDie()
+	config := params.Config
+	if config == nil {
+		config = ctrl.GetConfigOrDie()
+	}
 	osClient := osclientset.NewForConfigOrDie(rest.AddUserAgent(config, "cluster-api-provider-ovirt"))
+	var secretNamespace, secretName string
+	if params.LifecycleWebhookSecret != "" {
+		parts := strings.SplitN(params.LifecycleWebhookSecret, "/", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid lifecycle webhook secret %q, expected namespace/name", params.LifecycleWebhookSecret)
+		}
+		secretNamespace, secretName = parts[0], parts[1]
+	}
+
+	connections := clients.NewConnectionPool(params.Client, params.ConnectionPoolSize, params.ConnectionIdleTimeout)
+	connections.OnCertificateError = func(secret types.NamespacedName, err error) {
+		params.EventRecorder.Eventf(
+			&corev1.ObjectReference{Kind: "Secret", Namespace: secret.Namespace, Name: secret.Name},
+			corev1.EventTypeWarning, "EngineCertificateUntrusted",
+			"The engine certificate isn't trusted anymore, it was probably rotated. The connection is "+
+				"rebuilt with the CA sources read again, update them if the new CA isn't trusted yet: %v", err)
+	}
 
 	return &OvirtActuator{
-		params:         params,
-		client:         params.Client,
-		machinesClient: params.MachinesClient,
-		scheme:         params.Scheme,
-		KubeClient:     params.KubeClient,
-		EventRecorder:  params.EventRecorder,
-		ovirtApi:       nil,
-		OSClient:       osClient,
+		params:        params,
+		client:        params.Client,
+		scheme:        params.Scheme,
+		KubeClient:    params.KubeClient,
+		EventRecorder: params.EventRecorder,
+		connections:   connections,
+		OSClient:      osClient,
+		notifier:      notifier.New(params.LifecycleWebhookURL, params.Client, secretNamespace, secretName),
+		permissions:   clients.NewPermissionsChecker(params.Client),
 	}, nil
 }
 
+// Notifier returns the lifecycle webhook notifier, the manager runs it to post the events
+func (actuator *OvirtActuator) Notifier() *notifier.Notifier {
+	return actuator.notifier
+}
+
+// Connections returns the engine connection pool, the other controllers share it
+func (actuator *OvirtActuator) Connections() *clients.ConnectionPool {
+	return actuator.connections
+}
+
+// CheckPermissions probes the engine permits of the user of the credentials secret,
+// so missing permits are reported at startup rather than by the first failing machine.
+func (actuator *OvirtActuator) CheckPermissions(namespace, secretName string) error {
+	connection, err := actuator.getConnection(namespace, secretName)
+	if err != nil {
+		return err
+	}
+	defer actuator.connections.Release(connection)
+	_, err = actuator.permissions.Check(namespace, secretName, connection)
+	return err
+}
+
 func (actuator *OvirtActuator) Create(ctx context.Context, machine *machinev1.Machine) error {
 	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
 	if err != nil {
@@ -79,6 +183,7 @@ func (actuator *OvirtActuator) Create(ctx context.Context, machine *machinev1.Ma
 	if err != nil {
 		return fmt.Errorf("failed to create connection to oVirt API")
 	}
+	defer actuator.connections.Release(connection)
 
 	machineService, err := clients.NewInstanceServiceFromMachine(machine, connection)
 	if err != nil {
@@ -88,16 +193,66 @@ func (actuator *OvirtActuator) Create(ctx context.Context, machine *machinev1.Ma
 	if verr := actuator.validateMachine(machine, providerSpec); verr != nil {
 		return actuator.handleMachineError(machine, verr)
 	}
+	missing, err := actuator.permissions.Check(machine.Namespace, providerSpec.CredentialsSecret.Name, connection)
+	if err != nil {
+		klog.Warningf("Failed checking the engine permits of machine %s: %v", machine.Name, err)
+	} else if err := actuator.updateProviderConditions(ctx, machine, conditionPermitsGranted(missing)); err != nil {
+		klog.Warningf("Failed updating the permits condition of machine %s: %v", machine.Name, err)
+	}
 
-	// creating a new instance, we don't have the vm id yet
-	instance, err := machineService.GetVmByName()
+	if err := machineService.ResolveTemplate(providerSpec); err != nil {
+		return actuator.handleValidationError(machine, "template", err)
+	}
+	if err := machineService.ValidateDataCenter(providerSpec); err != nil {
+		return actuator.handleValidationError(machine, "data center", err)
+	}
+	if err := machineService.ValidateEmulatedMachine(providerSpec); err != nil {
+		return actuator.handleValidationError(machine, "emulated machine", err)
+	}
+	if err := machineService.ValidateStorage(providerSpec); err != nil {
+		return actuator.handleValidationError(machine, "OS disk storage", err)
+	}
+	cpus, memoryMB, err := machineService.InstanceTypeResources(providerSpec)
 	if err != nil {
-		return err
+		return actuator.handleValidationError(machine, "instance type", err)
+	}
+
+	// creating a new instance, we don't have the vm id yet
+	instance, ok := actuator.takeVMSnapshot(machine)
+	if !ok {
+		instance, err = machineService.GetVmByName()
+		if clients.IsOwnershipConflict(err) {
+			// creating the VM again would fail on the name, the conflict needs the admin
+			return actuator.handleMachineError(machine, apierrors.InvalidMachineConfiguration(
+				"Cannot create the VM of the machine: %v", err))
+		}
+		if err != nil {
+			return err
+		}
 	}
 	if instance != nil {
 		klog.Infof("Skipped creating a VM that already exists.\n")
 		return nil
 	}
+	if err := actuator.checkClusterQuota(ctx, machine, providerSpec); err != nil {
+		return err
+	}
+	actuator.checkStorageOvercommit(ctx, machine, providerSpec, machineService)
+
+	cloneStartTime := metav1.Now()
+	err = actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+		providerStatus.CloneStartTime = &cloneStartTime
+		providerStatus.CPUs = cpus
+		providerStatus.MemoryMB = memoryMB
+	})
+	if err != nil {
+		return fmt.Errorf("failed to record the creation time of machine %s: %v", machine.Name, err)
+	}
+	if cpus > 0 || memoryMB > 0 {
+		actuator.reconcileMachineSetCapacity(ctx, machine, cpus, memoryMB)
+	}
+	machineService.OnCreatePhase = actuator.createPhaseRecorder(ctx, machine)
+	machineService.DiskExtensionTimeout = actuator.params.DiskExtensionTimeout
 
 	instance, err = machineService.InstanceCreate(machine, providerSpec, actuator.KubeClient)
 	if err != nil {
@@ -105,41 +260,161 @@ func (actuator *OvirtActuator) Create(ctx context.Context, machine *machinev1.Ma
 			"error creating Ovirt instance: %v", err))
 	}
 
-	// Wait till ready
-	err = util.PollImmediate(RetryIntervalInstanceStatus, TimeoutInstanceCreate, func() (bool, error) {
-		instance, err := machineService.GetVm(*machine)
-		if err != nil {
-			return false, nil
-		}
-		return instance.MustStatus() == ovirtsdk.VMSTATUS_DOWN, nil
-	})
+	actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "Created", "Created Machine %v", machine.Name)
+	actuator.notifier.Notify(ctx, lifecycleEvent(notifier.MachineCreated, machine, providerSpec, instance))
+	if instance.MustStatus() != ovirtsdk.VMSTATUS_DOWN {
+		// the template disks are being cloned, Update resumes the creation once they are done
+		klog.Infof("Waiting for the template disks of machine %s to be cloned", machine.Name)
+		return &apierrors.RequeueAfterError{RequeueAfter: RetryIntervalInstanceStatus}
+	}
+	actuator.reportCloneProgress(ctx, machine, 100)
+
+	err = actuator.startInstance(ctx, machine, providerSpec, machineService, instance)
 	if err != nil {
-		return actuator.handleMachineError(machine, apierrors.CreateMachine(
-			"Error creating oVirt VM: %v", err))
+		return err
+	}
+
+	if _, err := actuator.patchMachine(ctx, machine, machineService, instance, conditionSuccess()); err != nil {
+		return err
 	}
+	actuator.reportAllocation(machine, providerSpec, machineService, instance)
+	return transientStateRequeue(instance)
+}
+
+// startInstance starts the created VM without waiting for it to run, Update
+// follows the VM till it is up. The VM is started with cloud-init or sysprep
+// if its initialization is for them, so the guest applies it.
+func (actuator *OvirtActuator) startInstance(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	machineService *clients.InstanceService,
+	instance *clients.Instance) error {
 
 	vmService := machineService.Connection.SystemService().VmsService().VmService(instance.MustId())
-	_, err = vmService.Start().Send()
+	start := vmService.Start()
+	switch providerSpec.InitializationType {
+	case ovirtconfigv1.InitializationTypeCloudInit:
+		start.UseCloudInit(true)
+	case ovirtconfigv1.InitializationTypeSysprep:
+		start.UseSysprep(true)
+	}
+	_, err := start.Send()
 	if err != nil {
+		if details, ok := clients.SchedulingFailureDetails(err); ok {
+			if cerr := actuator.updateProviderConditions(ctx, machine, conditionSchedulingFailed(details)); cerr != nil {
+				klog.Errorf("failed to set the scheduling failure condition on machine %s: %v", machine.Name, cerr)
+			}
+		}
 		return actuator.handleMachineError(machine, apierrors.CreateMachine(
 			"Error running oVirt VM: %v", err))
 	}
-
-	// Wait till running
-	err = util.PollImmediate(RetryIntervalInstanceStatus, TimeoutInstanceCreate, func() (bool, error) {
-		instance, err := machineService.GetVm(*machine)
-		if err != nil {
-			return false, nil
-		}
-		return instance.MustStatus() == ovirtsdk.VMSTATUS_UP, nil
+	startTime := metav1.Now()
+	err = actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+		providerStatus.CreatePhase = ovirtconfigv1.CreatePhaseVMStarted
+		providerStatus.StartTime = &startTime
 	})
+	if err != nil {
+		klog.Errorf("failed to record the start of machine %s: %v", machine.Name, err)
+	}
+	return nil
+}
+
+// checkStartTimeout fails the machine when its VM doesn't come up within the start timeout,
+// the start time is cleared once the VM is up or the machine failed
+func (actuator *OvirtActuator) checkStartTimeout(ctx context.Context, machine *machinev1.Machine, vm *clients.Instance) error {
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.StartTime == nil {
+		return nil
+	}
+	if vm.MustStatus() == ovirtsdk.VMSTATUS_UP {
+		return actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+			providerStatus.StartTime = nil
+		})
+	}
+	timeout := actuator.params.StartTimeout
+	if timeout <= 0 || time.Since(providerStatus.StartTime.Time) <= timeout || actuator.client == nil {
+		return nil
+	}
+	machineErr := apierrors.CreateMachine("timeout waiting for VM %s to come up, it is %s %v after its start",
+		vm.MustName(), vm.MustStatus(), timeout)
+
+	// The machine controller only fails the machines whose creation failed, the error of an
+	// update is retried. The machine is moved to the Failed phase here, so it isn't reconciled
+	// anymore and a MachineHealthCheck replaces it.
+	providerStatus.StartTime = nil
+	rawExtension, err := ovirtconfigv1.RawExtensionFromProviderStatus(providerStatus)
+	if err != nil {
+		return err
+	}
+	base := machine.DeepCopy()
+	phase := machinePhaseFailed
+	machine.Status.Phase = &phase
+	machine.Status.ErrorReason = &machineErr.Reason
+	machine.Status.ErrorMessage = &machineErr.Message
+	machine.Status.ProviderStatus = rawExtension
+	if err := actuator.client.Status().Patch(ctx, machine, client.MergeFrom(base)); err != nil {
+		return fmt.Errorf("unable to update machine status: %v", err)
+	}
+	actuator.reconcileMachineSetFailures(ctx, machine)
+	actuator.EventRecorder.Eventf(machine, corev1.EventTypeWarning, "StartTimeout", machineErr.Message)
+	klog.Errorf("Machine error %s: %v", machine.Name, machineErr.Message)
+	return machineErr
+}
+
+// resumeCreate completes a machine creation which was interrupted, e.g by a controller
+// restart, starting after the last creation phase recorded in the provider status.
+func (actuator *OvirtActuator) resumeCreate(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	machineService *clients.InstanceService,
+	instance *clients.Instance) error {
+
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return err
+	}
+	// machines created before the creation phases were recorded have no clone start time
+	if providerStatus.CloneStartTime == nil || providerStatus.CreatePhase == ovirtconfigv1.CreatePhaseVMStarted {
+		return nil
+	}
+	recordPhase := actuator.createPhaseRecorder(ctx, machine)
+	if instance.MustStatus() != ovirtsdk.VMSTATUS_DOWN {
+		// the VM was started, only the phase wasn't recorded
+		recordPhase(ovirtconfigv1.CreatePhaseVMStarted)
+		return nil
+	}
+
+	phase := providerStatus.CreatePhase
+	if phase == "" {
+		// the VM exists, so it was created
+		phase = ovirtconfigv1.CreatePhaseVMCreated
+	}
+	if phase == ovirtconfigv1.CreatePhaseVMCreated {
+		actuator.reportCloneProgress(ctx, machine, 100)
+	}
+	klog.Infof("Resuming the creation of machine %s after phase %s", machine.Name, phase)
+	machineService.OnCreatePhase = recordPhase
+	machineService.DiskExtensionTimeout = actuator.params.DiskExtensionTimeout
+	err = machineService.ConfigureInstance(machine, providerSpec, instance.Vm, phase)
 	if err != nil {
 		return actuator.handleMachineError(machine, apierrors.CreateMachine(
-			"Error running oVirt VM: %v", err))
+			"error creating Ovirt instance: %v", err))
 	}
+	return actuator.startInstance(ctx, machine, providerSpec, machineService, instance)
+}
 
-	actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "Created", "Updated Machine %v", machine.Name)
-	return actuator.patchMachine(ctx,machine, instance, conditionSuccess())
+// createPhaseRecorder returns a function recording the completed creation phase in the provider status
+func (actuator *OvirtActuator) createPhaseRecorder(ctx context.Context, machine *machinev1.Machine) func(ovirtconfigv1.CreatePhase) {
+	return func(phase ovirtconfigv1.CreatePhase) {
+		err := actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+			providerStatus.CreatePhase = phase
+		})
+		if err != nil {
+			klog.Errorf("failed to record creation phase %s of machine %s: %v", phase, machine.Name, err)
+		}
+	}
 }
 
 func (actuator *OvirtActuator) Exists(_ context.Context, machine *machinev1.Machine) (bool, error) {
@@ -154,18 +429,51 @@ func (actuator *OvirtActuator) Exists(_ context.Context, machine *machinev1.Mach
 	if err != nil {
 		return false, fmt.Errorf("failed to create connection to oVirt API")
 	}
+	defer actuator.connections.Release(connection)
 
 	machineService, err := clients.NewInstanceServiceFromMachine(machine, connection)
 	if err != nil {
 		return false, err
 	}
 	vm, err := machineService.GetVm(*machine)
+	if clients.IsOwnershipConflict(err) {
+		// the VM named after the machine isn't its VM, Create reports the conflict
+		klog.Warningf("Machine %s has no VM: %v", machine.Name, err)
+		return false, nil
+	}
 	if err != nil {
 		return false, err
 	}
+	// the machine controller calls Exists after Delete, no Create or Update takes the snapshot
+	if machine.DeletionTimestamp == nil {
+		actuator.vmSnapshots.Store(machine.UID, &vmSnapshot{machine: machine, instance: vm})
+	}
 	return vm != nil, err
 }
 
+// vmSnapshot is the VM of a machine fetched by Exists, the machine controller calls
+// Create or Update with the same machine object right after it in the same reconcile,
+// they reuse the VM instead of fetching it again.
+type vmSnapshot struct {
+	machine  *machinev1.Machine
+	instance *clients.Instance
+}
+
+// takeVMSnapshot returns the VM fetched by Exists in the current reconcile of the machine,
+// ok is false if there is none and the VM must be fetched. The snapshot is used once.
+func (actuator *OvirtActuator) takeVMSnapshot(machine *machinev1.Machine) (instance *clients.Instance, ok bool) {
+	value, found := actuator.vmSnapshots.LoadAndDelete(machine.UID)
+	if !found {
+		return nil, false
+	}
+	snapshot := value.(*vmSnapshot)
+	// a snapshot of another machine object was taken by an earlier reconcile
+	if snapshot.machine != machine {
+		return nil, false
+	}
+	return snapshot.instance, true
+}
+
 func (actuator *OvirtActuator) Update(ctx context.Context, machine *machinev1.Machine) error {
 	// eager update
 	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
@@ -178,14 +486,17 @@ func (actuator *OvirtActuator) Update(ctx context.Context, machine *machinev1.Ma
 	if err != nil {
 		return fmt.Errorf("failed to create connection to oVirt API")
 	}
+	defer actuator.connections.Release(connection)
 
 	machineService, err := clients.NewInstanceServiceFromMachine(machine, connection)
 	if err != nil {
 		return err
 	}
 
-	var vm *clients.Instance
-	if machine.Spec.ProviderID == nil || *machine.Spec.ProviderID == "" {
+	vm, ok := actuator.takeVMSnapshot(machine)
+	if ok {
+		klog.V(5).Infof("Reusing the VM of machine %s fetched by Exists", machine.Name)
+	} else if machine.Spec.ProviderID == nil || *machine.Spec.ProviderID == "" {
 		vm, err = machineService.GetVmByName()
 		if err != nil {
 			return actuator.handleMachineError(machine, apierrors.InvalidMachineConfiguration(
@@ -198,10 +509,37 @@ func (actuator *OvirtActuator) Update(ctx context.Context, machine *machinev1.Ma
 				"Cannot find a VM by id: %v", err))
 		}
 	}
-	return actuator.patchMachine(ctx,machine, vm, conditionSuccess())
+	if vm != nil && vm.MustStatus() == ovirtsdk.VMSTATUS_IMAGE_LOCKED {
+		return actuator.resumeCloneWait(ctx, machine, machineService)
+	}
+	if vm != nil {
+		if err := actuator.resumeCreate(ctx, machine, providerSpec, machineService, vm); err != nil {
+			return err
+		}
+		if err := actuator.checkStartTimeout(ctx, machine, vm); err != nil {
+			return err
+		}
+	}
+	previousState := machine.Annotations[InstanceStatusAnnotationKey]
+	conditions := []ovirtconfigv1.OvirtMachineProviderCondition{conditionSuccess()}
+	if condition := vmRenamedCondition(machine, vm); condition != nil {
+		conditions = append(conditions, *condition)
+	}
+	changed, err := actuator.patchMachine(ctx, machine, machineService, vm, conditions...)
+	if err != nil {
+		return err
+	}
+	actuator.reportAllocation(machine, providerSpec, machineService, vm)
+	if changed {
+		actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "Updated", "Updated Machine %v", machine.Name)
+	}
+	if vm != nil && vm.MustStatus() == ovirtsdk.VMSTATUS_UP && previousState != string(ovirtsdk.VMSTATUS_UP) {
+		actuator.notifier.Notify(ctx, lifecycleEvent(notifier.MachineRunning, machine, providerSpec, vm))
+	}
+	return transientStateRequeue(vm)
 }
 
-func (actuator *OvirtActuator) Delete(_ context.Context, machine *machinev1.Machine) error {
+func (actuator *OvirtActuator) Delete(ctx context.Context, machine *machinev1.Machine) error {
 	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
 	if err != nil {
 		return actuator.handleMachineError(machine, apierrors.InvalidMachineConfiguration(
@@ -211,81 +549,431 @@ func (actuator *OvirtActuator) Delete(_ context.Context, machine *machinev1.Mach
 	if err != nil {
 		return err
 	}
+	defer actuator.connections.Release(connection)
 
 	machineService, err := clients.NewInstanceServiceFromMachine(machine, connection)
 	if err != nil {
 		return err
 	}
+	machineService.OnDeleteProgress = func(reason, message string) {
+		actuator.EventRecorder.Event(machine, corev1.EventTypeNormal, reason, message)
+	}
 
 	instance, err := machineService.GetVm(*machine)
+	if clients.IsOwnershipConflict(err) {
+		// the VM named after the machine is owned by another machine, it isn't removed
+		klog.Warningf("Skipped deleting the VM of another machine: %v", err)
+		instance, err = nil, nil
+	}
 	if err != nil {
 		return err
 	}
 
 	if instance == nil {
 		klog.Infof("Skipped deleting a VM that is already deleted.\n")
+		actuator.reconcileMachineSetFailures(ctx, machine)
 		return nil
 	}
 
-	err = machineService.InstanceDelete(instance.MustId())
-	if err != nil {
+	if err := machineService.VerifyOwner(instance.Vm); err != nil {
 		return actuator.handleMachineError(machine, apierrors.DeleteMachine(
+			"refusing to delete a VM of another machine: %v", err))
+	}
+
+	_, force := machine.ObjectMeta.Annotations[ForceDeleteAnnotation]
+	if force {
+		actuator.EventRecorder.Eventf(machine, corev1.EventTypeWarning, "ForceDelete",
+			"Forcing the deletion of VM %s, skipping its graceful shutdown", instance.MustName())
+	} else if start, ok := deleteStartTime(machine); ok && actuator.params.DeleteTimeout > 0 &&
+		time.Since(start) > actuator.params.DeleteTimeout {
+		force = true
+		actuator.EventRecorder.Eventf(machine, corev1.EventTypeWarning, "ForceDelete",
+			"The deletion of VM %s didn't complete within %v, forcing it", instance.MustName(), actuator.params.DeleteTimeout)
+	}
+	if actuator.params.EvacuateBeforeDelete && !force {
+		drained, err := actuator.nodeDrained(ctx, machine)
+		if err != nil {
+			return err
+		}
+		if !drained {
+			klog.Infof("Deleting machine %s: waiting for node %s to be drained", machine.Name, machine.Status.NodeRef.Name)
+			return &apierrors.RequeueAfterError{RequeueAfter: RetryIntervalInstanceStatus}
+		}
+		if err := machineService.ReleaseAffinityGroups(instance.Vm); err != nil {
+			return actuator.handleMachineError(machine, apierrors.DeleteMachine(
+				"error releasing the affinity groups of Ovirt instance: %v", err))
+		}
+	}
+
+	start, err := actuator.recordDeleteStart(ctx, machine)
+	if err != nil {
+		return err
+	}
+	err = machineService.InstanceDelete(instance.Vm, force, actuator.shutdownDeadline(start, providerSpec))
+	if clients.IsInProgress(err) {
+		klog.Infof("Deleting machine %s: %v", machine.Name, err)
+		return &apierrors.RequeueAfterError{RequeueAfter: RetryIntervalInstanceStatus}
+	}
+	if err != nil {
+		// the machine is kept until its VM is removed, so it isn't taken as gone while the VM runs
+		if actuator.client != nil {
+			if cerr := actuator.updateProviderConditions(ctx, machine, conditionVMDeletionFailed(err)); cerr != nil {
+				klog.Errorf("failed to set the VM deletion condition on machine %s: %v", machine.Name, cerr)
+			}
+		}
+		_ = actuator.handleMachineError(machine, apierrors.DeleteMachine(
 			"error deleting Ovirt instance: %v", err))
+		return &apierrors.RequeueAfterError{RequeueAfter: RetryIntervalVMDeletion}
 	}
 
+	actuator.errorUpdates.Delete(machine.UID)
+	actuator.transientStates.Delete(machine.UID)
+	actuator.vmSnapshots.Delete(machine.UID)
+	actuator.deleteAllocation(machine, providerSpec)
+	// the failures of the removed machine aren't reported on its MachineSet anymore
+	actuator.reconcileMachineSetFailures(ctx, machine)
 	actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "Deleted", "Deleted Machine %v", machine.Name)
+	deleted := lifecycleEvent(notifier.MachineDeleted, machine, providerSpec, instance)
+	// the addresses are lost with the VM, they are recorded for the external DNS and IPAM cleanup
+	actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "AddressesReleased",
+		"vm=%s fqdn=%s addresses=%s", deleted.VMName, deleted.FQDN, formatAddresses(deleted.Addresses))
+	actuator.notifier.Notify(ctx, deleted)
 	return nil
 }
 
+// staticIPs returns the static addresses of the network configuration of the spec, the
+// addresses of the preferred family first
+func staticIPs(providerSpec *ovirtconfigv1.OvirtMachineProviderSpec, excludeAddr map[string]int) []string {
+	if providerSpec.NetworkConfiguration == nil {
+		return nil
+	}
+	var preferred, others []string
+	for _, nic := range providerSpec.NetworkConfiguration.Nics {
+		if _, ok := excludeAddr[nic.IP]; ok {
+			continue
+		}
+		ip := net.ParseIP(nic.IP)
+		switch {
+		case ip == nil:
+		case providerSpec.AddressFamily == ovirtconfigv1.AddressFamilyIPv6 && ip.To4() == nil,
+			providerSpec.AddressFamily == ovirtconfigv1.AddressFamilyIPv4 && ip.To4() != nil:
+			preferred = append(preferred, nic.IP)
+		default:
+			others = append(others, nic.IP)
+		}
+	}
+	return append(preferred, others...)
+}
+
+// shutdownDeadline returns the time the guest of the deleted machine may shut down
+// gracefully until, counted from the start of the VM removal, or a zero time if its
+// VM is powered off right away
+func (actuator *OvirtActuator) shutdownDeadline(start time.Time, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) time.Time {
+	timeout := actuator.params.ShutdownTimeout
+	if providerSpec.ShutdownTimeout != nil {
+		timeout = providerSpec.ShutdownTimeout.Duration
+	}
+	// a guest without a guest agent may ignore the shutdown
+	if timeout <= 0 || providerSpec.NoGuestAgent {
+		return time.Time{}
+	}
+	return start.Add(timeout)
+}
+
+// deleteStartTime returns the time the removal of the VM of the machine started, ok is
+// false if it didn't start yet
+func deleteStartTime(machine *machinev1.Machine) (start time.Time, ok bool) {
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.DeleteStartTime == nil {
+		return time.Time{}, false
+	}
+	return providerStatus.DeleteStartTime.Time, true
+}
+
+// recordDeleteStart returns the time the removal of the VM of the machine started, and
+// records the current time in the provider status on the first attempt, before the VM
+// is asked to shut down.
+func (actuator *OvirtActuator) recordDeleteStart(ctx context.Context, machine *machinev1.Machine) (time.Time, error) {
+	if start, ok := deleteStartTime(machine); ok {
+		return start, nil
+	}
+	now := metav1.Now()
+	if actuator.client == nil {
+		return now.Time, nil
+	}
+	err := actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+		providerStatus.DeleteStartTime = &now
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("failed recording the start of the deletion of machine %s: %v", machine.Name, err)
+	}
+	return now.Time, nil
+}
+
+// nodeDrained returns true if the node of the machine was cordoned by the machine
+// controller drain, or if there is no node to drain
+func (actuator *OvirtActuator) nodeDrained(ctx context.Context, machine *machinev1.Machine) (bool, error) {
+	if machine.Status.NodeRef == nil {
+		return true, nil
+	}
+	if _, exclude := machine.ObjectMeta.Annotations[apierrors.ExcludeNodeDrainingAnnotation]; exclude {
+		return true, nil
+	}
+	node := &corev1.Node{}
+	err := actuator.client.Get(ctx, client.ObjectKey{Name: machine.Status.NodeRef.Name}, node)
+	if k8serrors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed fetching node %s: %v", machine.Status.NodeRef.Name, err)
+	}
+	return node.Spec.Unschedulable, nil
+}
+
 // If the OvirtActuator has a client for updating Machine objects, this will set
 // the appropriate reason/message on the Machine.Status. If not, such as during
 // cluster installation, it will operate as a no-op. It also returns the
 // original error for convenience, so callers can do "return handleMachineError(...)".
+// Identical errors are written at most once per ErrorUpdateInterval, so a failing
+// machine retried by the controller doesn't write its status on every retry. A
+// changed error is always written.
 func (actuator *OvirtActuator) handleMachineError(machine *machinev1.Machine, err *apierrors.MachineError) error {
-	if actuator.client != nil {
+	if actuator.client != nil && actuator.shouldUpdateMachineError(machine, err) {
+		base := machine.DeepCopy()
 		machine.Status.ErrorReason = &err.Reason
 		machine.Status.ErrorMessage = &err.Message
-		if err := actuator.client.Update(context.TODO(), machine); err != nil {
+		if err := actuator.client.Status().Patch(context.TODO(), machine, client.MergeFrom(base)); err != nil {
 			return fmt.Errorf("unable to update machine status: %v", err)
 		}
+		actuator.errorUpdates.Store(machine.UID, errorUpdate{reason: err.Reason, message: err.Message, time: time.Now()})
+		actuator.reconcileMachineSetFailures(context.TODO(), machine)
 	}
 
 	klog.Errorf("Machine error %s: %v", machine.Name, err.Message)
 	return err
 }
 
-func (actuator *OvirtActuator) patchMachine(ctx context.Context,machine *machinev1.Machine, instance *clients.Instance, condition ovirtconfigv1.OvirtMachineProviderCondition) error {
+// handleValidationError handles an error checking the provider spec of the machine against
+// the engine: a spec the engine rejects is a machine configuration error, any other error,
+// e.g the engine is unreachable, is returned as is so the machine is reconciled again.
+func (actuator *OvirtActuator) handleValidationError(machine *machinev1.Machine, what string, err error) error {
+	if clients.IsInvalidSpec(err) {
+		return actuator.handleMachineError(machine, apierrors.InvalidMachineConfiguration("invalid %s: %v", what, err))
+	}
+	return fmt.Errorf("failed validating the %s of machine %s: %v", what, machine.Name, err)
+}
+
+// errorUpdate is the last error written to a machine status
+type errorUpdate struct {
+	reason  machinev1.MachineStatusError
+	message string
+	time    time.Time
+}
+
+// shouldUpdateMachineError returns false if the machine already carries the error,
+// or if the same error was written less than ErrorUpdateInterval ago
+func (actuator *OvirtActuator) shouldUpdateMachineError(machine *machinev1.Machine, err *apierrors.MachineError) bool {
+	if machine.Status.ErrorReason != nil && *machine.Status.ErrorReason == err.Reason &&
+		machine.Status.ErrorMessage != nil && *machine.Status.ErrorMessage == err.Message {
+		return false
+	}
+	if value, ok := actuator.errorUpdates.Load(machine.UID); ok {
+		last := value.(errorUpdate)
+		if last.reason == err.Reason && last.message == err.Message && time.Since(last.time) < ErrorUpdateInterval {
+			klog.V(3).Infof("Skipping the error update of machine %s, written at %v", machine.Name, last.time)
+			return false
+		}
+	}
+	return true
+}
+
+// allocation is the last allocation reported for a machine
+type allocation struct {
+	labels     prometheus.Labels
+	generation int64
+	vmID       string
+	diskSize   int64
+	fetched    time.Time
+}
+
+// reportAllocation exports the resources allocated to the machine VM for chargeback.
+// The provisioned disk size is fetched again only when the machine spec or its VM
+// changed, or after AllocationRefreshInterval, and the series of an earlier label
+// set of the machine are deleted. Nothing is reported for a machine without a VM.
+func (actuator *OvirtActuator) reportAllocation(
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	machineService *clients.InstanceService,
+	instance *clients.Instance) {
+
+	if instance == nil || instance.Vm == nil {
+		return
+	}
+	labels := allocationLabels(machine, providerSpec)
+	var last allocation
+	if value, ok := actuator.allocations.Load(machine.UID); ok {
+		last = value.(allocation)
+		if !equality.Semantic.DeepEqual(last.labels, labels) {
+			deleteAllocationMetrics(last.labels)
+			last = allocation{}
+		}
+	}
+
+	metrics.MachineVCPUs.With(labels).Set(float64(clients.VCPUs(instance.Vm)))
+	if memory, ok := instance.Memory(); ok {
+		metrics.MachineMemoryBytes.With(labels).Set(float64(memory))
+	}
+	current := allocation{labels: labels, generation: machine.Generation, vmID: instance.MustId()}
+	if last.labels != nil && last.generation == current.generation && last.vmID == current.vmID &&
+		time.Since(last.fetched) < AllocationRefreshInterval {
+		current.diskSize, current.fetched = last.diskSize, last.fetched
+	} else {
+		size, err := machineService.ProvisionedDiskSize(current.vmID)
+		if err != nil {
+			klog.Warningf("failed fetching the disks of machine %s, skipping the disk allocation: %v", machine.Name, err)
+			actuator.allocations.Store(machine.UID, allocation{labels: labels})
+			return
+		}
+		current.diskSize, current.fetched = size, time.Now()
+	}
+	metrics.MachineDiskProvisionedBytes.With(labels).Set(float64(current.diskSize))
+	actuator.allocations.Store(machine.UID, current)
+}
+
+// deleteAllocation deletes the allocation series of the machine
+func (actuator *OvirtActuator) deleteAllocation(machine *machinev1.Machine, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) {
+	if value, ok := actuator.allocations.Load(machine.UID); ok {
+		deleteAllocationMetrics(value.(allocation).labels)
+		actuator.allocations.Delete(machine.UID)
+	}
+	deleteAllocationMetrics(allocationLabels(machine, providerSpec))
+}
+
+func deleteAllocationMetrics(labels prometheus.Labels) {
+	metrics.MachineVCPUs.Delete(labels)
+	metrics.MachineMemoryBytes.Delete(labels)
+	metrics.MachineDiskProvisionedBytes.Delete(labels)
+}
+
+func lifecycleEvent(
+	transition notifier.Transition,
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	instance *clients.Instance) notifier.Event {
+
+	fqdn, _ := instance.Fqdn()
+	return notifier.Event{
+		Transition: transition,
+		Namespace:  machine.Namespace,
+		Machine:    machine.Name,
+		MachineSet: machine.Labels[machineSetLabel],
+		ClusterID:  providerSpec.ClusterId,
+		VMID:       instance.MustId(),
+		VMName:     instance.MustName(),
+		Addresses:  machine.Status.Addresses,
+		FQDN:       fqdn,
+	}
+}
+
+// formatAddresses returns the addresses as comma separated type:address pairs
+func formatAddresses(addresses []corev1.NodeAddress) string {
+	formatted := make([]string, len(addresses))
+	for i, address := range addresses {
+		formatted[i] = string(address.Type) + ":" + address.Address
+	}
+	return strings.Join(formatted, ",")
+}
+
+func allocationLabels(machine *machinev1.Machine, providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) prometheus.Labels {
+	template := providerSpec.TemplateName
+	if template == "" {
+		template = providerSpec.TemplateID
+	}
+	return prometheus.Labels{
+		"namespace":  machine.Namespace,
+		"machine":    machine.Name,
+		"machineset": machine.Labels[machineSetLabel],
+		"cluster_id": providerSpec.ClusterId,
+		"template":   template,
+	}
+}
+
+// patchMachine reconciles the machine with the VM and writes it back, the returned
+// bool is false when nothing changed and the writes were skipped.
+func (actuator *OvirtActuator) patchMachine(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	machineService *clients.InstanceService,
+	instance *clients.Instance,
+	conditions ...ovirtconfigv1.OvirtMachineProviderCondition) (bool, error) {
+
+	if instance == nil || instance.Vm == nil {
+		// the VM was removed since the machine controller found it, Exists reports it next
+		return false, fmt.Errorf("the VM of machine %s wasn't found", machine.Name)
+	}
+	original := machine.DeepCopy()
 	actuator.reconcileProviderID(machine, instance)
 	klog.V(5).Infof("Machine %s provider status %s", instance.MustName(), instance.MustStatus())
 
-	err := actuator.reconcileNetwork(ctx,machine, instance)
+	networkCondition, err := actuator.reconcileNetwork(ctx, machine, machineService, instance)
 	if err != nil {
-		return err
+		return false, err
 	}
 	actuator.reconcileAnnotations(machine, instance)
-	err = actuator.reconcileProviderStatus(machine, instance, condition)
+	actuator.reconcileNodeLabels(machine, machineService)
+	err = actuator.reconcileProviderStatus(machine, instance, append(conditions, networkCondition)...)
 	if err != nil {
-		return err
+		return false, err
 	}
 
-	// Copy the status, because its discarded and returned fresh from the DB by the machine resource update.
-	// Save it for the status sub-resource update.
-	statusCopy := *machine.Status.DeepCopy()
-	klog.Info("Updating machine resource")
+	// the machine reconciled successfully, clear the errors of earlier attempts
+	machine.Status.ErrorReason = nil
+	machine.Status.ErrorMessage = nil
+	actuator.errorUpdates.Delete(machine.UID)
 
-	// TODO the namespace should be set on actuator creation. Remove the hardcoded openshift-machine-api.
-	newMachine, err := actuator.machinesClient.Machines("openshift-machine-api").Update(context.TODO(), machine, metav1.UpdateOptions{})
-	if err != nil {
-		return err
+	if equality.Semantic.DeepEqual(original.Spec, machine.Spec) &&
+		equality.Semantic.DeepEqual(original.Annotations, machine.Annotations) &&
+		machineStatusEqual(&original.Status, &machine.Status) {
+		klog.V(5).Infof("Machine %s is up to date", machine.Name)
+		return false, nil
 	}
 
-	newMachine.Status = statusCopy
-	klog.Info("Updating machine status sub-resource")
-	if _, err := actuator.machinesClient.Machines("openshift-machine-api").UpdateStatus(context.TODO(), newMachine, metav1.UpdateOptions{}); err != nil {
-		return err
+	// The machine and its status sub-resource are merge patched with the changes of this
+	// reconcile only, so the writes of the machine controller and of the other controllers
+	// in between don't conflict with them.
+	// The status is discarded and returned fresh from the DB by the machine resource patch,
+	// save it for the status sub-resource patch.
+	status := machine.Status.DeepCopy()
+	klog.Info("Patching machine resource")
+	if err := actuator.client.Patch(ctx, machine, client.MergeFrom(original)); err != nil {
+		return false, err
 	}
-	actuator.EventRecorder.Eventf(newMachine, corev1.EventTypeNormal, "Update", "Updated Machine %v", newMachine.Name)
-	return nil
+
+	statusBase := machine.DeepCopy()
+	statusBase.Status = original.Status
+	machine.Status = *status
+	klog.Info("Patching machine status sub-resource")
+	if err := actuator.client.Status().Patch(ctx, machine, client.MergeFrom(statusBase)); err != nil {
+		return false, err
+	}
+	if original.Status.ErrorReason != nil {
+		actuator.reconcileMachineSetFailures(ctx, machine)
+	}
+	return true, nil
+}
+
+// machineStatusEqual compares the machine statuses with their provider statuses decoded,
+// the provider status read back from the API server isn't byte for byte the one written,
+// e.g its fields are reordered
+func machineStatusEqual(a, b *machinev1.MachineStatus) bool {
+	aProviderStatus, aErr := ovirtconfigv1.ProviderStatusFromRawExtension(a.ProviderStatus)
+	bProviderStatus, bErr := ovirtconfigv1.ProviderStatusFromRawExtension(b.ProviderStatus)
+	if aErr != nil || bErr != nil {
+		return equality.Semantic.DeepEqual(a, b)
+	}
+	aStatus, bStatus := a.DeepCopy(), b.DeepCopy()
+	aStatus.ProviderStatus, bStatus.ProviderStatus = nil, nil
+	return equality.Semantic.DeepEqual(aStatus, bStatus) && equality.Semantic.DeepEqual(aProviderStatus, bProviderStatus)
 }
 
 func (actuator *OvirtActuator) getClusterAddress(ctx context.Context) (map[string]int,error){
@@ -302,53 +990,107 @@ func (actuator *OvirtActuator) getClusterAddress(ctx context.Context) (map[strin
 		return clusterAddr,nil
 	}
 
-func (actuator *OvirtActuator) reconcileNetwork(ctx context.Context,machine *machinev1.Machine, instance *clients.Instance) error {
+// reconcileNetwork sets the machine addresses and returns the AddressesReported
+// condition explaining why addresses are missing, e.g while the VM is down.
+func (actuator *OvirtActuator) reconcileNetwork(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	machineService *clients.InstanceService,
+	instance *clients.Instance) (ovirtconfigv1.OvirtMachineProviderCondition, error) {
+
 	switch instance.MustStatus() {
 	// expect IP addresses only on those statuses.
 	// in those statuses we 'll try reconciling
 	case ovirtsdk.VMSTATUS_UP, ovirtsdk.VMSTATUS_MIGRATING:
-		break
+		actuator.transientStates.Delete(machine.UID)
 
 	// update machine status.
 	case ovirtsdk.VMSTATUS_DOWN:
-		return nil
+		actuator.transientStates.Delete(machine.UID)
+		return conditionWaitingForVMStart(), nil
 
-	// return error if vm is transient state this will force retry reconciling until VM is up.
+	// keep the addresses while the vm is in a transient state, the machine is requeued
+	// by transientStateRequeue till it is up.
 	// there is no event generated that will trigger this.  BZ1854787
 	default:
-		return fmt.Errorf("Aborting reconciliation while VM %s  state is %s", instance.MustName(), instance.MustStatus())
-
+		return actuator.transientStateCondition(machine, instance), nil
 	}
 	name := instance.MustName()
 	addresses := []corev1.NodeAddress{{Address: name, Type: corev1.NodeInternalDNS}}
-	machineService, err := clients.NewInstanceServiceFromMachine(machine, actuator.ovirtApi)
-	if err != nil {
-		return err
-	}
 	vmId := instance.MustId()
-	klog.V(5).Infof("using oVirt SDK to find % IP addresses", name)
+	klog.V(5).Infof("using oVirt SDK to find %s IP addresses", name)
 
 	//get API and ingress addresses that will be excluded from the node address selection
 	excludeAddr, err := actuator.getClusterAddress(ctx)
 	if err != nil {
-		return err
+		return ovirtconfigv1.OvirtMachineProviderCondition{}, err
 	}
 
-	ip, err := machineService.FindVirtualMachineIP(vmId,excludeAddr)
+	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return ovirtconfigv1.OvirtMachineProviderCondition{}, err
+	}
+	var ips []string
+	if providerSpec.NoGuestAgent {
+		// there is no guest agent to report the addresses
+		ips = staticIPs(providerSpec, excludeAddr)
+	} else {
+		ips, err = machineService.FindVirtualMachineIPs(vmId, excludeAddr, providerSpec.AddressFamily)
+	}
 
 	if err != nil {
 		// stop reconciliation till we get IP addresses - otherwise the state will be considered stable.
 		klog.Errorf("failed to lookup the VM IP %s - skip setting addresses for this machine", err)
-		return err
+		return ovirtconfigv1.OvirtMachineProviderCondition{}, err
 	} else {
-		klog.V(5).Infof("received IP address %v from engine", ip)
-		addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip})
+		klog.V(5).Infof("received IP addresses %v from engine", ips)
+		for _, ip := range ips {
+			addresses = append(addresses, corev1.NodeAddress{Type: corev1.NodeInternalIP, Address: ip})
+		}
 	}
 	machine.Status.Addresses = addresses
-	return nil
+	return conditionAddressesReported(), nil
+}
+
+// transientStateCondition returns the AddressesReported condition of a machine whose
+// VM is in a transient state. The state is logged and reported by an event only when
+// it changes, not on every reconcile while the VM settles.
+func (actuator *OvirtActuator) transientStateCondition(machine *machinev1.Machine, instance *clients.Instance) ovirtconfigv1.OvirtMachineProviderCondition {
+	status := instance.MustStatus()
+	if previous, ok := actuator.transientStates.Load(machine.UID); !ok || previous.(ovirtsdk.VmStatus) != status {
+		actuator.transientStates.Store(machine.UID, status)
+		klog.Infof("Waiting for VM %s of machine %s, it is %s", instance.MustName(), machine.Name, status)
+		actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "WaitingForVMState",
+			"VM %s is %s, the machine addresses are reported once the VM settles", instance.MustName(), status)
+	} else {
+		klog.V(5).Infof("VM %s of machine %s is still %s", instance.MustName(), machine.Name, status)
+	}
+	return conditionWaitingForVMState(instance.MustName(), status)
 }
 
-func (actuator *OvirtActuator) reconcileProviderStatus(machine *machinev1.Machine, instance *clients.Instance, condition ovirtconfigv1.OvirtMachineProviderCondition) error {
+// transientStateRequeue returns the RequeueAfterError checking on a VM in a transient
+// state once it had time to settle, or nil if the VM isn't in a transient state. The
+// machine is patched before, so the WaitingForVMState condition is reported meanwhile.
+func transientStateRequeue(instance *clients.Instance) error {
+	if instance == nil {
+		return nil
+	}
+	requeueAfter := RetryIntervalTransientState
+	switch instance.MustStatus() {
+	case ovirtsdk.VMSTATUS_UP, ovirtsdk.VMSTATUS_MIGRATING, ovirtsdk.VMSTATUS_DOWN:
+		return nil
+	case ovirtsdk.VMSTATUS_WAIT_FOR_LAUNCH, ovirtsdk.VMSTATUS_POWERING_UP,
+		ovirtsdk.VMSTATUS_POWERING_DOWN, ovirtsdk.VMSTATUS_REBOOT_IN_PROGRESS:
+		requeueAfter = RetryIntervalInstanceStatus
+	}
+	return &apierrors.RequeueAfterError{RequeueAfter: requeueAfter}
+}
+
+func (actuator *OvirtActuator) reconcileProviderStatus(
+	machine *machinev1.Machine,
+	instance *clients.Instance,
+	conditions ...ovirtconfigv1.OvirtMachineProviderCondition) error {
+
 	status := string(instance.MustStatus())
 	name := instance.MustId()
 
@@ -358,7 +1100,12 @@ func (actuator *OvirtActuator) reconcileProviderStatus(machine *machinev1.Machin
 	}
 	providerStatus.InstanceState = &status
 	providerStatus.InstanceID = &name
-	providerStatus.Conditions = actuator.reconcileConditions(providerStatus.Conditions, condition)
+	for _, condition := range conditions {
+		providerStatus.Conditions = actuator.reconcileConditions(providerStatus.Conditions, condition)
+		if condition.Type == ovirtconfigv1.MachineCreated && condition.Status == corev1.ConditionTrue {
+			providerStatus.Conditions = actuator.resolveFailureConditions(providerStatus.Conditions)
+		}
+	}
 	rawExtension, err := ovirtconfigv1.RawExtensionFromProviderStatus(providerStatus)
 	if err != nil {
 		return err
@@ -367,6 +1114,48 @@ func (actuator *OvirtActuator) reconcileProviderStatus(machine *machinev1.Machin
 	return nil
 }
 
+// vmRenamedCondition returns the VMRenamed condition of the machine if the VM name differs
+// from the machine name, or if the VM got the machine name back, else nil. The VM found by
+// the ID of the provider ID is managed anyway, the condition makes the rename visible.
+func vmRenamedCondition(machine *machinev1.Machine, vm *clients.Instance) *ovirtconfigv1.OvirtMachineProviderCondition {
+	if vm == nil {
+		return nil
+	}
+	if name := vm.MustName(); name != machine.Name {
+		condition := conditionVMRenamed(name)
+		return &condition
+	}
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return nil
+	}
+	for _, c := range providerStatus.Conditions {
+		if c.Type == ovirtconfigv1.VMRenamed && c.Status == corev1.ConditionTrue {
+			condition := conditionVMNameMatching()
+			return &condition
+		}
+	}
+	return nil
+}
+
+// resolveFailureConditions flips the conditions left by earlier failed attempts
+// once the machine is successfully created
+func (actuator *OvirtActuator) resolveFailureConditions(
+	conditions []ovirtconfigv1.OvirtMachineProviderCondition) []ovirtconfigv1.OvirtMachineProviderCondition {
+
+	for _, c := range conditions {
+		switch {
+		case c.Type == ovirtconfigv1.SchedulingFailed && c.Status == corev1.ConditionTrue:
+			conditions = actuator.reconcileConditions(conditions, conditionScheduled())
+		case c.Type == ovirtconfigv1.TemplateCloned && c.Status != corev1.ConditionTrue:
+			conditions = actuator.reconcileConditions(conditions, conditionTemplateCloned(100))
+		case c.Type == ovirtconfigv1.ClusterQuotaExceeded && c.Status == corev1.ConditionTrue:
+			conditions = actuator.reconcileConditions(conditions, conditionClusterQuotaAvailable())
+		}
+	}
+	return conditions
+}
+
 func (actuator *OvirtActuator) reconcileProviderID(machine *machinev1.Machine, instance *clients.Instance) {
 	id := instance.MustId()
 	providerID := ovirt.ProviderIDPrefix + id
@@ -389,59 +1178,466 @@ func (actuator *OvirtActuator) reconcileConditions(
 		return []ovirtconfigv1.OvirtMachineProviderCondition{newCondition}
 	}
 
-	for _, c := range conditions {
+	for i := range conditions {
+		c := &conditions[i]
 		if c.Type == newCondition.Type {
 			if c.Reason != newCondition.Reason || c.Message != newCondition.Message {
 				if c.Status != newCondition.Status {
-					newCondition.LastTransitionTime = metav1.Now()
+					c.LastTransitionTime = metav1.Now()
 				}
 				c.Status = newCondition.Status
 				c.Message = newCondition.Message
 				c.Reason = newCondition.Reason
-				c.LastProbeTime = newCondition.LastProbeTime
-				return conditions
+				c.LastProbeTime = metav1.Now()
 			}
+			return conditions
 		}
 	}
-	return conditions
+	now := metav1.Now()
+	newCondition.LastProbeTime = now
+	newCondition.LastTransitionTime = now
+	return append(conditions, newCondition)
 }
 
-func (actuator *OvirtActuator) validateMachine(machine *machinev1.Machine, config *ovirtconfigv1.OvirtMachineProviderSpec) *apierrors.MachineError {
-	return nil
+// updateProviderConditions sets the condition on the machine provider status and
+// updates the status sub-resource. It is used when a failure happens before there
+// is an instance to patch the machine with.
+func (actuator *OvirtActuator) updateProviderConditions(ctx context.Context, machine *machinev1.Machine, condition ovirtconfigv1.OvirtMachineProviderCondition) error {
+	return actuator.updateProviderStatus(ctx, machine, func(providerStatus *ovirtconfigv1.OvirtMachineProviderStatus) {
+		providerStatus.Conditions = actuator.reconcileConditions(providerStatus.Conditions, condition)
+	})
+}
+
+// updateProviderStatus applies mutate on the machine provider status and patches the
+// status sub-resource, the machine is refreshed with the patched one for later updates.
+func (actuator *OvirtActuator) updateProviderStatus(ctx context.Context, machine *machinev1.Machine, mutate func(*ovirtconfigv1.OvirtMachineProviderStatus)) error {
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return err
+	}
+	mutate(providerStatus)
+	rawExtension, err := ovirtconfigv1.RawExtensionFromProviderStatus(providerStatus)
+	if err != nil {
+		return err
+	}
+	base := machine.DeepCopy()
+	machine.Status.ProviderStatus = rawExtension
+	return actuator.client.Status().Patch(ctx, machine, client.MergeFrom(base))
+}
+
+// checkClusterQuota fails the creation of the machine when the oVirt cluster of the machine
+// already holds the maximum number of machines of its quota, protecting shared clusters
+// from runaway autoscaling. The machines which are deleted or whose creation didn't start
+// aren't counted.
+func (actuator *OvirtActuator) checkClusterQuota(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec) error {
+
+	quota, ok := actuator.params.ClusterMachineQuota[providerSpec.ClusterId]
+	if !ok || actuator.client == nil {
+		return nil
+	}
+	machines := &machinev1.MachineList{}
+	if err := actuator.client.List(ctx, machines, client.InNamespace(machine.Namespace)); err != nil {
+		return fmt.Errorf("failed listing the machines to check the quota of cluster %s: %v", providerSpec.ClusterId, err)
+	}
+	count := 0
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.UID == machine.UID || m.DeletionTimestamp != nil || !creationStarted(m) {
+			continue
+		}
+		spec, err := ovirtconfigv1.ProviderSpecFromRawExtension(m.Spec.ProviderSpec.Value)
+		if err != nil || spec.ClusterId != providerSpec.ClusterId {
+			continue
+		}
+		count++
+	}
+	if count < quota {
+		return nil
+	}
+	if err := actuator.updateProviderConditions(ctx, machine, conditionClusterQuotaExceeded(providerSpec.ClusterId, quota)); err != nil {
+		klog.Errorf("failed to set the cluster quota condition on machine %s: %v", machine.Name, err)
+	}
+	return actuator.handleMachineError(machine, apierrors.CreateMachine(
+		"oVirt cluster %s already holds its maximum of %d machines", providerSpec.ClusterId, quota))
 }
 
-//createApiConnection returns a a client to oVirt's API endpoint
-func createApiConnection(client client.Client, namespace string, secretName string) (*ovirtsdk.Connection, error) {
-	creds, err := clients.GetCredentialsSecret(client, namespace, secretName)
+// creationStarted returns true if the VM of the machine was created, or is being created
+func creationStarted(machine *machinev1.Machine) bool {
+	if machine.Spec.ProviderID != nil && *machine.Spec.ProviderID != "" {
+		return true
+	}
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	return err == nil && providerStatus.CloneStartTime != nil
+}
 
+// reconcileMachineSetFailures sets the FailedMachinesAnnotation of the MachineSet of the
+// machine from the error reasons of its machines, and emits a warning event on the
+// MachineSet when they change. The annotation is removed once no machine fails.
+// The deleted machines aren't counted, Delete recomputes the failures once the VM of
+// a deleted machine is removed so its error isn't reported anymore.
+func (actuator *OvirtActuator) reconcileMachineSetFailures(ctx context.Context, machine *machinev1.Machine) {
+	machineSetName := machine.Labels[machineSetLabel]
+	if machineSetName == "" || actuator.client == nil {
+		return
+	}
+	machines := &machinev1.MachineList{}
+	err := actuator.client.List(ctx, machines,
+		client.InNamespace(machine.Namespace), client.MatchingLabels{machineSetLabel: machineSetName})
 	if err != nil {
-		klog.Infof("failed getting credentials for namespace %s, %s", namespace, err)
-		return nil, err
+		klog.Warningf("Failed listing the machines of MachineSet %s, skipping its failures: %v", machineSetName, err)
+		return
 	}
+	failures := make(map[string]int)
+	for i := range machines.Items {
+		m := &machines.Items[i]
+		if m.UID == machine.UID {
+			// the cache may not hold the status written by this reconcile yet
+			m = machine
+		}
+		if m.DeletionTimestamp == nil && m.Status.ErrorReason != nil {
+			failures[string(*m.Status.ErrorReason)]++
+		}
+	}
+	reasons := make([]string, 0, len(failures))
+	for reason, count := range failures {
+		reasons = append(reasons, fmt.Sprintf("%s=%d", reason, count))
+	}
+	sort.Strings(reasons)
+	value := strings.Join(reasons, ",")
 
-	connection, err := ovirtsdk.NewConnectionBuilder().
-		URL(creds.URL).
-		Username(creds.Username).
-		Password(creds.Password).
-		CAFile(creds.CAFile).
-		Insecure(creds.Insecure).
-		Build()
+	machineSet := &machinev1.MachineSet{}
+	err = actuator.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machineSetName}, machineSet)
 	if err != nil {
-		return nil, err
+		klog.Warningf("Failed fetching MachineSet %s, skipping its failures: %v", machineSetName, err)
+		return
 	}
+	if machineSet.Annotations[FailedMachinesAnnotation] == value {
+		return
+	}
+	base := machineSet.DeepCopy()
+	if value == "" {
+		delete(machineSet.Annotations, FailedMachinesAnnotation)
+	} else {
+		if machineSet.Annotations == nil {
+			machineSet.Annotations = make(map[string]string)
+		}
+		machineSet.Annotations[FailedMachinesAnnotation] = value
+	}
+	if err := actuator.client.Patch(ctx, machineSet, client.MergeFrom(base)); err != nil {
+		klog.Warningf("Failed updating the failures of MachineSet %s: %v", machineSetName, err)
+		return
+	}
+	if value != "" {
+		actuator.EventRecorder.Eventf(machineSet, corev1.EventTypeWarning, "MachinesFailing",
+			"Machines of MachineSet %s are failing: %s", machineSetName, value)
+	}
+}
 
-	return connection, nil
+// reconcileMachineSetCapacity sets the CPUAnnotation and the MemoryMBAnnotation of the
+// MachineSet of the machine to the resources of the instance type of the machine, so the
+// tools sizing the MachineSet don't have to look the instance type up in the engine.
+func (actuator *OvirtActuator) reconcileMachineSetCapacity(ctx context.Context, machine *machinev1.Machine, cpus int32, memoryMB int64) {
+	machineSetName := machine.Labels[machineSetLabel]
+	if machineSetName == "" || actuator.client == nil {
+		return
+	}
+	machineSet := &machinev1.MachineSet{}
+	err := actuator.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machineSetName}, machineSet)
+	if err != nil {
+		klog.Warningf("Failed fetching MachineSet %s, skipping its capacity: %v", machineSetName, err)
+		return
+	}
+	capacity := map[string]string{
+		CPUAnnotation:      strconv.Itoa(int(cpus)),
+		MemoryMBAnnotation: strconv.FormatInt(memoryMB, 10),
+	}
+	base := machineSet.DeepCopy()
+	changed := false
+	for key, value := range capacity {
+		if machineSet.Annotations[key] == value {
+			continue
+		}
+		if machineSet.Annotations == nil {
+			machineSet.Annotations = make(map[string]string)
+		}
+		machineSet.Annotations[key] = value
+		changed = true
+	}
+	if !changed {
+		return
+	}
+	if err := actuator.client.Patch(ctx, machineSet, client.MergeFrom(base)); err != nil {
+		klog.Warningf("Failed updating the capacity of MachineSet %s: %v", machineSetName, err)
+	}
 }
 
-//getConnection returns a a client to oVirt's API endpoint
-func (actuator *OvirtActuator) getConnection(namespace, secretName string) (*ovirtsdk.Connection, error) {
-	var err error
-	if actuator.ovirtApi == nil || actuator.ovirtApi.Test() != nil {
-		// session expired or some other error, re-login.
-		actuator.ovirtApi, err = createApiConnection(actuator.client, namespace, secretName)
+// checkStorageOvercommit emits a warning event on the MachineSet of the machine when
+// the OS disks of its machines which are still created would take more than the
+// storage overcommit threshold of the free space of their storage domain.
+func (actuator *OvirtActuator) checkStorageOvercommit(
+	ctx context.Context,
+	machine *machinev1.Machine,
+	providerSpec *ovirtconfigv1.OvirtMachineProviderSpec,
+	machineService *clients.InstanceService) {
+
+	threshold := actuator.params.StorageOvercommitThreshold
+	machineSetName := machine.Labels[machineSetLabel]
+	if threshold <= 0 || machineSetName == "" || actuator.client == nil {
+		return
+	}
+	machineSet := &machinev1.MachineSet{}
+	err := actuator.client.Get(ctx, client.ObjectKey{Namespace: machine.Namespace, Name: machineSetName}, machineSet)
+	if err != nil {
+		klog.Warningf("Failed fetching the MachineSet of machine %s, skipping the storage check: %v", machine.Name, err)
+		return
+	}
+	machines := &machinev1.MachineList{}
+	err = actuator.client.List(ctx, machines,
+		client.InNamespace(machine.Namespace), client.MatchingLabels{machineSetLabel: machineSetName})
+	if err != nil {
+		klog.Warningf("Failed listing the machines of MachineSet %s, skipping the storage check: %v", machineSetName, err)
+		return
+	}
+	pending := 0
+	for _, m := range machines.Items {
+		if m.Spec.ProviderID == nil || *m.Spec.ProviderID == "" {
+			pending++
+		}
+	}
+	sd, diskSize, err := machineService.OSDiskStorageEstimate(providerSpec)
+	if err != nil {
+		klog.Warningf("Failed estimating the storage of machine %s, skipping the storage check: %v", machine.Name, err)
+		return
+	}
+	available, ok := sd.Available()
+	if !ok {
+		return
+	}
+	required := int64(pending) * diskSize
+	if required*100 <= available*int64(threshold) {
+		return
+	}
+	actuator.EventRecorder.Eventf(machineSet, corev1.EventTypeWarning, "StorageOvercommit",
+		"Creating %d machines requires %d GiB on storage domain %s, over %d%% of its %d GiB free space",
+		pending, required>>30, sd.MustName(), threshold, available>>30)
+}
+
+// reportCloneProgress records the template disks clone progress as a condition and an event
+func (actuator *OvirtActuator) reportCloneProgress(ctx context.Context, machine *machinev1.Machine, progress int) {
+	actuator.EventRecorder.Eventf(machine, corev1.EventTypeNormal, "Cloning",
+		"Cloning template disks of machine %v: %d%% completed", machine.Name, progress)
+	if err := actuator.updateProviderConditions(ctx, machine, conditionTemplateCloned(progress)); err != nil {
+		klog.Errorf("failed to update the clone progress of machine %s: %v", machine.Name, err)
+	}
+}
+
+// resumeCloneWait follows a template disks clone which was started by an earlier
+// Create, possibly before a controller restart. The timeout window is kept from the
+// original creation time. It returns nil while the clone is in progress, the machine
+// controller requeues the machine until it is provisioned.
+func (actuator *OvirtActuator) resumeCloneWait(ctx context.Context, machine *machinev1.Machine, machineService *clients.InstanceService) error {
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		return err
+	}
+	timeout := actuator.params.CreateTimeout
+	if timeout == 0 {
+		timeout = clients.DefaultCloneTimeout
+	}
+	if providerStatus.CloneStartTime != nil && time.Since(providerStatus.CloneStartTime.Time) > timeout {
+		return actuator.handleMachineError(machine, apierrors.CreateMachine(
+			"timeout waiting for the template disks of machine %s to be cloned", machine.Name))
+	}
+	progress, err := machineService.CloneProgress()
+	if err != nil {
+		klog.Warningf("failed to fetch the clone progress of machine %s: %v", machine.Name, err)
+	} else if progress >= 0 {
+		actuator.reportCloneProgress(ctx, machine, progress)
+	}
+	return nil
+}
+
+func (actuator *OvirtActuator) validateMachine(machine *machinev1.Machine, config *ovirtconfigv1.OvirtMachineProviderSpec) *apierrors.MachineError {
+	if config.TemplateName == "" && config.TemplateTag == "" && config.TemplateID == "" {
+		return apierrors.InvalidMachineConfiguration("the template ID, name or tag must be set")
+	}
+	if config.InstanceTypeId != "" && config.InstanceTypeName != "" {
+		return apierrors.InvalidMachineConfiguration("only one of the instance type ID and name can be set")
+	}
+	if config.TemplateID != "" && config.TemplateVersion != "" {
+		return apierrors.InvalidMachineConfiguration("the template version can't be set with the template ID, the ID selects the version")
+	}
+	if config.TemplateVersion != "" && config.TemplateVersion != ovirtconfigv1.TemplateVersionLatest {
+		if n, err := strconv.Atoi(config.TemplateVersion); err != nil || n < 1 {
+			return apierrors.InvalidMachineConfiguration("invalid template version %q, expected a version number or %q",
+				config.TemplateVersion, ovirtconfigv1.TemplateVersionLatest)
+		}
+	}
+	nicNames := make(map[string]bool)
+	for i, nic := range config.NetworkInterfaces {
+		if nic == nil || (nic.VNICProfileID == "") == (nic.VNICProfileName == "") {
+			return apierrors.InvalidMachineConfiguration("a network interface must set either the vNIC profile ID or name")
+		}
+		name := nic.Name
+		if name == "" {
+			name = fmt.Sprintf("nic%d", i+1)
+		}
+		if nicNames[name] {
+			return apierrors.InvalidMachineConfiguration("network interface name %s is used more than once", name)
+		}
+		nicNames[name] = true
+	}
+	switch config.InitializationType {
+	case "", ovirtconfigv1.InitializationTypeIgnition, ovirtconfigv1.InitializationTypeCloudInit,
+		ovirtconfigv1.InitializationTypeSysprep:
+	default:
+		return apierrors.InvalidMachineConfiguration("initialization type must be %s, %s or %s, got %s",
+			ovirtconfigv1.InitializationTypeIgnition, ovirtconfigv1.InitializationTypeCloudInit,
+			ovirtconfigv1.InitializationTypeSysprep, config.InitializationType)
+	}
+	switch config.AddressFamily {
+	case "", ovirtconfigv1.AddressFamilyIPv4, ovirtconfigv1.AddressFamilyIPv6:
+	default:
+		return apierrors.InvalidMachineConfiguration("address family must be %s or %s, got %s",
+			ovirtconfigv1.AddressFamilyIPv4, ovirtconfigv1.AddressFamilyIPv6, config.AddressFamily)
+	}
+	switch config.Hugepages {
+	case 0, ovirtconfigv1.Hugepages2M, ovirtconfigv1.Hugepages1G:
+	default:
+		return apierrors.InvalidMachineConfiguration("hugepages must be %d or %d KiB, got %d",
+			ovirtconfigv1.Hugepages2M, ovirtconfigv1.Hugepages1G, config.Hugepages)
+	}
+	if config.GuaranteedMemoryMB < 0 || (config.MemoryMB > 0 && config.GuaranteedMemoryMB > config.MemoryMB) {
+		return apierrors.InvalidMachineConfiguration("guaranteed memory %d MiB must be between 0 and the memory %d MiB",
+			config.GuaranteedMemoryMB, config.MemoryMB)
+	}
+	if config.MaxMemoryMB < 0 || (config.MaxMemoryMB > 0 && config.MaxMemoryMB < config.MemoryMB) {
+		return apierrors.InvalidMachineConfiguration("max memory %d MiB must be at least the memory %d MiB",
+			config.MaxMemoryMB, config.MemoryMB)
+	}
+	switch config.ResumeBehavior {
+	case "", ovirtconfigv1.ResumeBehaviorAutoResume, ovirtconfigv1.ResumeBehaviorLeavePaused, ovirtconfigv1.ResumeBehaviorKill:
+	default:
+		return apierrors.InvalidMachineConfiguration("resume behavior must be %s, %s or %s, got %s",
+			ovirtconfigv1.ResumeBehaviorAutoResume, ovirtconfigv1.ResumeBehaviorLeavePaused,
+			ovirtconfigv1.ResumeBehaviorKill, config.ResumeBehavior)
+	}
+	if config.Lease != nil && config.ResumeBehavior != "" && config.ResumeBehavior != ovirtconfigv1.ResumeBehaviorKill {
+		return apierrors.InvalidMachineConfiguration("a VM with a lease must have the %s resume behavior, got %s",
+			ovirtconfigv1.ResumeBehaviorKill, config.ResumeBehavior)
+	}
+	if config.HAPriority < 0 {
+		return apierrors.InvalidMachineConfiguration("invalid HA priority %d", config.HAPriority)
+	}
+	if config.ShutdownTimeout != nil && config.ShutdownTimeout.Duration < 0 {
+		return apierrors.InvalidMachineConfiguration("invalid shutdown timeout %v", config.ShutdownTimeout.Duration)
+	}
+	if config.CPUShares < 0 || config.CPUShares > maxCPUShares {
+		return apierrors.InvalidMachineConfiguration("invalid CPU shares %d, expected 0 to %d", config.CPUShares, maxCPUShares)
+	}
+	if config.Stateless && (config.HighlyAvailable || config.Lease != nil) {
+		return apierrors.InvalidMachineConfiguration("a stateless VM can't be highly available")
+	}
+	for _, label := range config.AffinityLabels {
+		if label == "" {
+			return apierrors.InvalidMachineConfiguration("an affinity label name must not be empty")
+		}
+	}
+	if config.IOThreads < 0 {
+		return apierrors.InvalidMachineConfiguration("invalid number of IO threads %d", config.IOThreads)
+	}
+	if config.SerialNumber != nil {
+		switch ovirtsdk.SerialNumberPolicy(config.SerialNumber.Policy) {
+		case ovirtsdk.SERIALNUMBERPOLICY_CUSTOM:
+			if config.SerialNumber.Value == "" {
+				return apierrors.InvalidMachineConfiguration("the custom serial number policy requires a value")
+			}
+		case ovirtsdk.SERIALNUMBERPOLICY_HOST, ovirtsdk.SERIALNUMBERPOLICY_VM, ovirtsdk.SERIALNUMBERPOLICY_NONE:
+			if config.SerialNumber.Value != "" {
+				return apierrors.InvalidMachineConfiguration("a serial number value requires the %q policy",
+					ovirtsdk.SERIALNUMBERPOLICY_CUSTOM)
+			}
+		default:
+			return apierrors.InvalidMachineConfiguration("invalid serial number policy %q, expected one of %q, %q, %q or %q",
+				config.SerialNumber.Policy, ovirtsdk.SERIALNUMBERPOLICY_HOST, ovirtsdk.SERIALNUMBERPOLICY_VM,
+				ovirtsdk.SERIALNUMBERPOLICY_CUSTOM, ovirtsdk.SERIALNUMBERPOLICY_NONE)
+		}
+	}
+	if config.RNG != nil {
+		switch ovirtsdk.RngSource(config.RNG.Source) {
+		case ovirtsdk.RNGSOURCE_URANDOM, ovirtsdk.RNGSOURCE_HWRNG:
+		default:
+			return apierrors.InvalidMachineConfiguration("rng source must be %s or %s, got %s",
+				ovirtsdk.RNGSOURCE_URANDOM, ovirtsdk.RNGSOURCE_HWRNG, config.RNG.Source)
+		}
+		if config.RNG.RateBytes < 0 || config.RNG.RatePeriodMs < 0 ||
+			(config.RNG.RateBytes > 0 && config.RNG.RatePeriodMs == 0) {
+			return apierrors.InvalidMachineConfiguration("invalid rng rate of %d bytes per %d ms",
+				config.RNG.RateBytes, config.RNG.RatePeriodMs)
+		}
+	}
+	switch ovirtsdk.BiosType(config.BiosType) {
+	case "", ovirtsdk.BIOSTYPE_CLUSTER_DEFAULT, ovirtsdk.BIOSTYPE_I440FX_SEA_BIOS, ovirtsdk.BIOSTYPE_Q35_SEA_BIOS,
+		ovirtsdk.BIOSTYPE_Q35_OVMF, ovirtsdk.BIOSTYPE_Q35_SECURE_BOOT:
+	default:
+		return apierrors.InvalidMachineConfiguration("bios type must be %s, %s, %s, %s or %s, got %s",
+			ovirtsdk.BIOSTYPE_CLUSTER_DEFAULT, ovirtsdk.BIOSTYPE_I440FX_SEA_BIOS, ovirtsdk.BIOSTYPE_Q35_SEA_BIOS,
+			ovirtsdk.BIOSTYPE_Q35_OVMF, ovirtsdk.BIOSTYPE_Q35_SECURE_BOOT, config.BiosType)
+	}
+	bootDevices := make(map[string]bool, len(config.BootDevices))
+	for _, device := range config.BootDevices {
+		switch device {
+		case ovirtconfigv1.BootDeviceHD, ovirtconfigv1.BootDeviceNetwork, ovirtconfigv1.BootDeviceCDROM:
+		default:
+			return apierrors.InvalidMachineConfiguration("boot device must be %s, %s or %s, got %s",
+				ovirtconfigv1.BootDeviceHD, ovirtconfigv1.BootDeviceNetwork, ovirtconfigv1.BootDeviceCDROM, device)
+		}
+		if bootDevices[device] {
+			return apierrors.InvalidMachineConfiguration("boot device %s is listed more than once", device)
+		}
+		bootDevices[device] = true
+	}
+	if err := clients.ValidateConsole(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid console: %v", err)
+	}
+	if err := clients.ValidatePlacement(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid placement: %v", err)
+	}
+	if err := clients.ValidateCPUPinning(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid cpu pinning: %v", err)
+	}
+	if err := clients.ValidateNUMANodes(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid NUMA nodes: %v", err)
+	}
+	if err := clients.ValidateCustomProperties(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid custom properties: %v", err)
 	}
+	if err := clients.ValidateCompatibilityVersion(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("%v", err)
+	}
+	if err := clients.ValidateNetworkConfiguration(config); err != nil {
+		return apierrors.InvalidMachineConfiguration("invalid network configuration: %v", err)
+	}
+	if config.NoGuestAgent && (config.NetworkConfiguration == nil || len(config.NetworkConfiguration.Nics) == 0) {
+		return apierrors.InvalidMachineConfiguration("a machine without a guest agent must declare its static addresses in the network configuration")
+	}
+	if config.NoGuestAgent && config.ShutdownTimeout != nil && config.ShutdownTimeout.Duration > 0 {
+		return apierrors.InvalidMachineConfiguration("a machine without a guest agent can't be shut down gracefully, the shutdown timeout must be 0")
+	}
+	return nil
+}
 
-	return actuator.ovirtApi, err
+//getConnection returns a a client to oVirt's API endpoint, it must be released back to the pool
+func (actuator *OvirtActuator) getConnection(namespace, secretName string) (*ovirtsdk.Connection, error) {
+	connection, err := actuator.connections.Get(namespace, secretName)
+	if err != nil {
+		klog.Infof("failed getting a connection for namespace %s, %s", namespace, err)
+		return nil, err
+	}
+	return connection, nil
 }
 
 func (actuator *OvirtActuator) reconcileAnnotations(machine *machinev1.Machine, instance *clients.Instance) {
@@ -451,6 +1647,46 @@ func (actuator *OvirtActuator) reconcileAnnotations(machine *machinev1.Machine,
 	machine.ObjectMeta.Annotations[InstanceStatusAnnotationKey] = string(instance.MustStatus())
 }
 
+// reconcileNodeLabels sets the CPU type of the oVirt cluster as labels of the machine,
+// which the machine API propagates to its node, so workloads depending on a CPU
+// generation can be scheduled to the right machines
+func (actuator *OvirtActuator) reconcileNodeLabels(machine *machinev1.Machine, machineService *clients.InstanceService) {
+	cpu, err := actuator.getClusterCPU(machineService)
+	if err != nil {
+		klog.Warningf("Failed fetching the cluster CPU type of machine %s, skipping its node labels: %v", machine.Name, err)
+		return
+	}
+	if machine.Spec.Labels == nil {
+		machine.Spec.Labels = make(map[string]string)
+	}
+	for key, value := range cpu.NodeLabels() {
+		machine.Spec.Labels[key] = value
+	}
+}
+
+// clusterCPU is the CPU type of an oVirt cluster and the time it was fetched
+type clusterCPU struct {
+	cpu     *clients.ClusterCPU
+	fetched time.Time
+}
+
+// getClusterCPU returns the CPU type of the cluster of the machine, fetched at most
+// once per ClusterCPURefreshInterval, as it only changes on a cluster upgrade
+func (actuator *OvirtActuator) getClusterCPU(machineService *clients.InstanceService) (*clients.ClusterCPU, error) {
+	if value, ok := actuator.clusterCPUs.Load(machineService.ClusterId); ok {
+		cached := value.(clusterCPU)
+		if time.Since(cached.fetched) < ClusterCPURefreshInterval {
+			return cached.cpu, nil
+		}
+	}
+	cpu, err := machineService.GetClusterCPU(machineService.ClusterId)
+	if err != nil {
+		return nil, err
+	}
+	actuator.clusterCPUs.Store(machineService.ClusterId, clusterCPU{cpu: cpu, fetched: time.Now()})
+	return cpu, nil
+}
+
 func conditionSuccess() ovirtconfigv1.OvirtMachineProviderCondition {
 	return ovirtconfigv1.OvirtMachineProviderCondition{
 		Type:    ovirtconfigv1.MachineCreated,
@@ -468,3 +1704,127 @@ func conditionFailed() ovirtconfigv1.OvirtMachineProviderCondition {
 		Message: "Machine creation failed",
 	}
 }
+
+func conditionSchedulingFailed(details string) ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.SchedulingFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  "NoHostSatisfiesConstraints",
+		Message: details,
+	}
+}
+
+func conditionPermitsGranted(missing []string) ovirtconfigv1.OvirtMachineProviderCondition {
+	if len(missing) > 0 {
+		return ovirtconfigv1.OvirtMachineProviderCondition{
+			Type:    ovirtconfigv1.PermitsGranted,
+			Status:  corev1.ConditionFalse,
+			Reason:  "PermitsMissing",
+			Message: fmt.Sprintf("The engine user is missing the permits %s", strings.Join(missing, ", ")),
+		}
+	}
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.PermitsGranted,
+		Status:  corev1.ConditionTrue,
+		Reason:  "PermitsGranted",
+		Message: "The engine user has all the required permits",
+	}
+}
+
+func conditionClusterQuotaExceeded(clusterID string, quota int) ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.ClusterQuotaExceeded,
+		Status:  corev1.ConditionTrue,
+		Reason:  "ClusterQuotaExceeded",
+		Message: fmt.Sprintf("The oVirt cluster %s already holds its maximum of %d machines", clusterID, quota),
+	}
+}
+
+func conditionVMDeletionFailed(err error) ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.VMDeletionFailed,
+		Status:  corev1.ConditionTrue,
+		Reason:  "VMRemovalFailed",
+		Message: fmt.Sprintf("Failed removing the VM, retrying in %v: %v", RetryIntervalVMDeletion, err),
+	}
+}
+
+func conditionVMRenamed(name string) ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.VMRenamed,
+		Status:  corev1.ConditionTrue,
+		Reason:  "VMRenamed",
+		Message: fmt.Sprintf("The VM was renamed to %s in the engine, it is managed by its ID", name),
+	}
+}
+
+func conditionVMNameMatching() ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.VMRenamed,
+		Status:  corev1.ConditionFalse,
+		Reason:  "VMNameMatching",
+		Message: "The VM has the name of the machine",
+	}
+}
+
+func conditionClusterQuotaAvailable() ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.ClusterQuotaExceeded,
+		Status:  corev1.ConditionFalse,
+		Reason:  "ClusterQuotaAvailable",
+		Message: "The machine was created within the quota of its oVirt cluster",
+	}
+}
+
+func conditionAddressesReported() ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.AddressesReported,
+		Status:  corev1.ConditionTrue,
+		Reason:  "AddressesFound",
+		Message: "VM IP addresses reported by the guest agent",
+	}
+}
+
+func conditionWaitingForVMStart() ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.AddressesReported,
+		Status:  corev1.ConditionFalse,
+		Reason:  "VMDown",
+		Message: "Waiting for VM to start",
+	}
+}
+
+func conditionWaitingForVMState(name string, status ovirtsdk.VmStatus) ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.AddressesReported,
+		Status:  corev1.ConditionFalse,
+		Reason:  "WaitingForVMState",
+		Message: fmt.Sprintf("Waiting for VM %s to settle, it is %s", name, status),
+	}
+}
+
+func conditionScheduled() ovirtconfigv1.OvirtMachineProviderCondition {
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.SchedulingFailed,
+		Status:  corev1.ConditionFalse,
+		Reason:  "Scheduled",
+		Message: "VM successfully scheduled on a host",
+	}
+}
+
+func conditionTemplateCloned(progress int) ovirtconfigv1.OvirtMachineProviderCondition {
+	if progress >= 100 {
+		return ovirtconfigv1.OvirtMachineProviderCondition{
+			Type:    ovirtconfigv1.TemplateCloned,
+			Status:  corev1.ConditionTrue,
+			Reason:  "CloneSucceeded",
+			Message: "Template disks successfully cloned",
+		}
+	}
+	return ovirtconfigv1.OvirtMachineProviderCondition{
+		Type:    ovirtconfigv1.TemplateCloned,
+		Status:  corev1.ConditionFalse,
+		Reason:  "CloneInProgress",
+		Message: fmt.Sprintf("Cloning template disks, %d%% completed", progress),
+	}
+}
@@ -0,0 +1,325 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package machine
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	apierrors "github.com/openshift/machine-api-operator/pkg/controller/machine"
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+)
+
+func TestStaticIPs(t *testing.T) {
+	nics := func(ips ...string) *ovirtconfigv1.NetworkConfiguration {
+		config := &ovirtconfigv1.NetworkConfiguration{}
+		for _, ip := range ips {
+			config.Nics = append(config.Nics, ovirtconfigv1.NicConfiguration{Name: "eth0", IP: ip})
+		}
+		return config
+	}
+	for _, tc := range []struct {
+		name          string
+		config        *ovirtconfigv1.NetworkConfiguration
+		addressFamily string
+		excludeAddr   map[string]int
+		want          []string
+	}{
+		{
+			name: "no network configuration",
+		},
+		{
+			name:   "spec order without a family",
+			config: nics("fd00::10", "192.168.1.10"),
+			want:   []string{"fd00::10", "192.168.1.10"},
+		},
+		{
+			name:          "IPv4 first",
+			config:        nics("fd00::10", "192.168.1.10", "fd00::11"),
+			addressFamily: ovirtconfigv1.AddressFamilyIPv4,
+			want:          []string{"192.168.1.10", "fd00::10", "fd00::11"},
+		},
+		{
+			name:          "IPv6 first",
+			config:        nics("192.168.1.10", "fd00::10"),
+			addressFamily: ovirtconfigv1.AddressFamilyIPv6,
+			want:          []string{"fd00::10", "192.168.1.10"},
+		},
+		{
+			name:   "invalid addresses skipped",
+			config: nics("", "not-an-ip", "192.168.1.10"),
+			want:   []string{"192.168.1.10"},
+		},
+		{
+			name:        "cluster addresses excluded",
+			config:      nics("192.168.1.10", "192.168.1.11"),
+			excludeAddr: map[string]int{"192.168.1.10": 1},
+			want:        []string{"192.168.1.11"},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			spec := &ovirtconfigv1.OvirtMachineProviderSpec{
+				NetworkConfiguration: tc.config,
+				AddressFamily:        tc.addressFamily,
+			}
+			if got := staticIPs(spec, tc.excludeAddr); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("staticIPs() = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestVMRenamedCondition(t *testing.T) {
+	machine := func(conditions ...ovirtconfigv1.OvirtMachineProviderCondition) *machinev1.Machine {
+		m := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}
+		if len(conditions) > 0 {
+			raw, err := ovirtconfigv1.RawExtensionFromProviderStatus(&ovirtconfigv1.OvirtMachineProviderStatus{Conditions: conditions})
+			if err != nil {
+				t.Fatal(err)
+			}
+			m.Status.ProviderStatus = raw
+		}
+		return m
+	}
+	vm := func(name string) *clients.Instance {
+		return &clients.Instance{Vm: ovirtsdk.NewVmBuilder().Name(name).MustBuild()}
+	}
+	for _, tc := range []struct {
+		name    string
+		machine *machinev1.Machine
+		vm      *clients.Instance
+		want    *corev1.ConditionStatus
+		reason  string
+	}{
+		{
+			name:    "no VM",
+			machine: machine(),
+		},
+		{
+			name:    "VM with the machine name",
+			machine: machine(),
+			vm:      vm("worker-0"),
+		},
+		{
+			name:    "renamed VM",
+			machine: machine(),
+			vm:      vm("worker-0-renamed"),
+			want:    conditionStatus(corev1.ConditionTrue),
+			reason:  "VMRenamed",
+		},
+		{
+			name:    "renamed VM got the machine name back",
+			machine: machine(conditionVMRenamed("worker-0-renamed")),
+			vm:      vm("worker-0"),
+			want:    conditionStatus(corev1.ConditionFalse),
+			reason:  "VMNameMatching",
+		},
+		{
+			name:    "name matching already reported",
+			machine: machine(conditionVMNameMatching()),
+			vm:      vm("worker-0"),
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := vmRenamedCondition(tc.machine, tc.vm)
+			switch {
+			case got == nil && tc.want == nil:
+			case got == nil || tc.want == nil:
+				t.Fatalf("vmRenamedCondition() = %v, want status %v", got, tc.want)
+			case got.Type != ovirtconfigv1.VMRenamed || got.Status != *tc.want || got.Reason != tc.reason:
+				t.Errorf("vmRenamedCondition() = %s %s %s, want %s %s %s",
+					got.Type, got.Status, got.Reason, ovirtconfigv1.VMRenamed, *tc.want, tc.reason)
+			}
+		})
+	}
+}
+
+func TestTransientStateRequeue(t *testing.T) {
+	for _, tc := range []struct {
+		status ovirtsdk.VmStatus
+		want   time.Duration
+	}{
+		{status: ovirtsdk.VMSTATUS_UP},
+		{status: ovirtsdk.VMSTATUS_MIGRATING},
+		{status: ovirtsdk.VMSTATUS_DOWN},
+		{status: ovirtsdk.VMSTATUS_POWERING_UP, want: RetryIntervalInstanceStatus},
+		{status: ovirtsdk.VMSTATUS_REBOOT_IN_PROGRESS, want: RetryIntervalInstanceStatus},
+		{status: ovirtsdk.VMSTATUS_PAUSED, want: RetryIntervalTransientState},
+		{status: ovirtsdk.VMSTATUS_NOT_RESPONDING, want: RetryIntervalTransientState},
+	} {
+		t.Run(string(tc.status), func(t *testing.T) {
+			err := transientStateRequeue(&clients.Instance{Vm: ovirtsdk.NewVmBuilder().Status(tc.status).MustBuild()})
+			requeue, ok := err.(*apierrors.RequeueAfterError)
+			switch {
+			case tc.want == 0 && err != nil:
+				t.Errorf("transientStateRequeue() = %v, want nil", err)
+			case tc.want != 0 && (!ok || requeue.RequeueAfter != tc.want):
+				t.Errorf("transientStateRequeue() = %v, want a requeue after %v", err, tc.want)
+			}
+		})
+	}
+}
+
+func TestCheckStartTimeout(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	start := metav1.NewTime(time.Now().Add(-time.Hour))
+	raw, err := ovirtconfigv1.RawExtensionFromProviderStatus(&ovirtconfigv1.OvirtMachineProviderStatus{StartTime: &start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine := &machinev1.Machine{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "worker-0"},
+		Status:     machinev1.MachineStatus{ProviderStatus: raw},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(machine.DeepCopy()).Build()
+	actuator := &OvirtActuator{
+		client:        c,
+		EventRecorder: &record.FakeRecorder{},
+		params:        ovirt.ActuatorParams{StartTimeout: 10 * time.Minute},
+	}
+	vm := &clients.Instance{Vm: ovirtsdk.NewVmBuilder().Name("worker-0").Status(ovirtsdk.VMSTATUS_DOWN).MustBuild()}
+
+	if err := actuator.checkStartTimeout(context.TODO(), machine, vm); err == nil {
+		t.Fatal("checkStartTimeout() of a VM down an hour after its start = nil, want an error")
+	}
+	if err := c.Get(context.TODO(), client.ObjectKeyFromObject(machine), machine); err != nil {
+		t.Fatal(err)
+	}
+	if machine.Status.Phase == nil || *machine.Status.Phase != machinePhaseFailed {
+		t.Errorf("phase of a machine whose VM didn't come up = %v, want %s", machine.Status.Phase, machinePhaseFailed)
+	}
+	if machine.Status.ErrorReason == nil || *machine.Status.ErrorReason != machinev1.CreateMachineError {
+		t.Errorf("error reason of a machine whose VM didn't come up = %v, want %s", machine.Status.ErrorReason, machinev1.CreateMachineError)
+	}
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if providerStatus.StartTime != nil {
+		t.Errorf("start time of the failed machine = %v, want it cleared", providerStatus.StartTime)
+	}
+}
+
+func TestUpdateWithoutVM(t *testing.T) {
+	actuator := &OvirtActuator{}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0", UID: "uid-0"}}
+	if _, err := actuator.patchMachine(context.TODO(), machine, nil, nil); err == nil {
+		t.Error("patchMachine() of a machine without a VM = nil, want an error")
+	}
+	actuator.reportAllocation(machine, &ovirtconfigv1.OvirtMachineProviderSpec{}, nil, nil)
+	if _, ok := actuator.allocations.Load(machine.UID); ok {
+		t.Error("reportAllocation() of a machine without a VM recorded an allocation")
+	}
+}
+
+func TestRecordDeleteStart(t *testing.T) {
+	actuator := &OvirtActuator{params: ovirt.ActuatorParams{ShutdownTimeout: 5 * time.Minute}}
+	machine := &machinev1.Machine{ObjectMeta: metav1.ObjectMeta{Name: "worker-0"}}
+	if _, ok := deleteStartTime(machine); ok {
+		t.Fatal("deleteStartTime() of a machine whose deletion didn't start is set")
+	}
+
+	start := metav1.NewTime(time.Now().Add(-time.Hour).Truncate(time.Second))
+	raw, err := ovirtconfigv1.RawExtensionFromProviderStatus(&ovirtconfigv1.OvirtMachineProviderStatus{DeleteStartTime: &start})
+	if err != nil {
+		t.Fatal(err)
+	}
+	machine.Status.ProviderStatus = raw
+	// the recorded start is kept, the shutdown window isn't restarted
+	got, err := actuator.recordDeleteStart(context.TODO(), machine)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !got.Equal(start.Time) {
+		t.Errorf("recordDeleteStart() = %v, want the recorded %v", got, start.Time)
+	}
+
+	spec := &ovirtconfigv1.OvirtMachineProviderSpec{}
+	if deadline := actuator.shutdownDeadline(got, spec); !deadline.Equal(start.Add(5 * time.Minute)) {
+		t.Errorf("shutdownDeadline() = %v, want %v", deadline, start.Add(5*time.Minute))
+	}
+	spec.NoGuestAgent = true
+	if deadline := actuator.shutdownDeadline(got, spec); !deadline.IsZero() {
+		t.Errorf("shutdownDeadline() of a VM without a guest agent = %v, want a zero time", deadline)
+	}
+}
+
+func TestMachineStatusEqual(t *testing.T) {
+	status := func(raw string) *machinev1.MachineStatus {
+		return &machinev1.MachineStatus{ProviderStatus: &runtime.RawExtension{Raw: []byte(raw)}}
+	}
+	written := status(`{"instanceId":"vm-0","instanceState":"up","metadata":{}}`)
+	if !machineStatusEqual(written, status(`{"metadata":{"creationTimestamp":null},"instanceState":"up","instanceId":"vm-0"}`)) {
+		t.Error("machineStatusEqual() of a provider status read back with its fields reordered is false")
+	}
+	if machineStatusEqual(written, status(`{"instanceId":"vm-0","instanceState":"down"}`)) {
+		t.Error("machineStatusEqual() of provider statuses with different instance states is true")
+	}
+}
+
+func TestReconcileMachineSetFailuresOfDeletedMachine(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	reason := machinev1.CreateMachineError
+	failing := func(name string) *machinev1.Machine {
+		return &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				UID:       types.UID(name),
+				Labels:    map[string]string{machineSetLabel: "workers"},
+			},
+			Status: machinev1.MachineStatus{ErrorReason: &reason},
+		}
+	}
+	machineSet := &machinev1.MachineSet{ObjectMeta: metav1.ObjectMeta{
+		Namespace:   "ns",
+		Name:        "workers",
+		Annotations: map[string]string{FailedMachinesAnnotation: string(reason) + "=2"},
+	}}
+	c := fake.NewClientBuilder().WithScheme(scheme).
+		WithRuntimeObjects(machineSet, failing("worker-0"), failing("worker-1")).Build()
+	actuator := &OvirtActuator{client: c, EventRecorder: &record.FakeRecorder{}}
+
+	// the deleted machine is still listed while its finalizer is removed
+	deleted := failing("worker-0")
+	now := metav1.Now()
+	deleted.DeletionTimestamp = &now
+	actuator.reconcileMachineSetFailures(context.TODO(), deleted)
+
+	if err := c.Get(context.TODO(), client.ObjectKeyFromObject(machineSet), machineSet); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := machineSet.Annotations[FailedMachinesAnnotation], string(reason)+"=1"; got != want {
+		t.Errorf("%s = %q after the deletion of a failing machine, want %q", FailedMachinesAnnotation, got, want)
+	}
+}
+
+func conditionStatus(status corev1.ConditionStatus) *corev1.ConditionStatus {
+	return &status
+}
@@ -0,0 +1,276 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package machine
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	k8sruntime "k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+)
+
+const (
+	// scaleMachines is the number of machines the scale benchmark reconciles
+	scaleMachines = 500
+
+	engineAPIPath = "/ovirt-engine/api"
+	benchCluster  = "cluster-0"
+	benchCPUType  = "Intel Cascadelake Server Family"
+)
+
+// fakeEngine is an oVirt engine API stub serving the calls of the actuator Update of a
+// running VM, and the infrastructure of the OpenShift cluster. It counts the engine calls.
+type fakeEngine struct {
+	*httptest.Server
+
+	mu    sync.Mutex
+	calls int
+}
+
+func newFakeEngine(tb testing.TB) *fakeEngine {
+	e := &fakeEngine{}
+	e.Server = httptest.NewServer(http.HandlerFunc(e.serve))
+	tb.Cleanup(e.Close)
+	return e
+}
+
+func (e *fakeEngine) serve(w http.ResponseWriter, r *http.Request) {
+	path := r.URL.Path
+	if path == "/apis/config.openshift.io/v1/infrastructures/cluster" {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"apiVersion":"config.openshift.io/v1","kind":"Infrastructure","metadata":{"name":"cluster"},`+
+			`"status":{"platformStatus":{"type":"oVirt","ovirt":{"apiServerInternalIP":"10.0.0.1","ingressIP":"10.0.0.2"}}}}`)
+		return
+	}
+
+	e.mu.Lock()
+	e.calls++
+	e.mu.Unlock()
+	switch {
+	case path == "/ovirt-engine/sso/oauth/token":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"access_token":"fake-token"}`)
+	case path == "/ovirt-engine/services/sso-logout":
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{}`)
+	case path == engineAPIPath:
+		writeXML(w, `<api><product_info><name>oVirt Engine</name></product_info></api>`)
+	case path == engineAPIPath+"/clusters/"+benchCluster:
+		writeXML(w, `<cluster id="`+benchCluster+`"><name>Default</name><cpu><type>`+benchCPUType+`</type>`+
+			`<architecture>x86_64</architecture></cpu><version><major>4</major><minor>6</minor></version></cluster>`)
+	case path == engineAPIPath+"/clusterlevels/4.6":
+		writeXML(w, `<cluster_level id="4.6"><cpu_types><cpu_type><name>`+benchCPUType+`</name>`+
+			`<level>10</level><architecture>x86_64</architecture></cpu_type></cpu_types></cluster_level>`)
+	case strings.HasPrefix(path, engineAPIPath+"/vms/"):
+		id := strings.TrimPrefix(path, engineAPIPath+"/vms/")
+		switch {
+		case strings.HasSuffix(id, "/reporteddevices"):
+			var n int
+			fmt.Sscanf(id, "vm-%d/reporteddevices", &n)
+			writeXML(w, fmt.Sprintf(`<reported_devices><reported_device id="dev-0"><name>eth0</name><ips>`+
+				`<ip><address>10.0.%d.%d</address><version>v4</version></ip></ips></reported_device></reported_devices>`,
+				1+n/250, 1+n%250))
+		case strings.HasSuffix(id, "/diskattachments"):
+			writeXML(w, `<disk_attachments></disk_attachments>`)
+		default:
+			writeXML(w, fmt.Sprintf(`<vm href="%s/vms/%s" id="%s"><name>worker-%s</name><status>up</status>`+
+				`<memory>8589934592</memory><cpu><topology><cores>2</cores><sockets>2</sockets><threads>1</threads>`+
+				`</topology></cpu><cluster id="%s"/></vm>`, engineAPIPath, id, id, strings.TrimPrefix(id, "vm-"), benchCluster))
+		}
+	default:
+		w.Header().Set("Content-Type", "application/xml")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `<fault><reason>Operation Failed</reason><detail>Not found: `+path+`</detail></fault>`)
+	}
+}
+
+// callCount returns the number of engine calls served so far
+func (e *fakeEngine) callCount() int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls
+}
+
+func writeXML(w http.ResponseWriter, body string) {
+	w.Header().Set("Content-Type", "application/xml")
+	fmt.Fprint(w, `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>`+body)
+}
+
+// writeCountingClient counts the API server writes of the wrapped client, the machine
+// and status patches of the actuator
+type writeCountingClient struct {
+	client.Client
+
+	mu     sync.Mutex
+	writes int
+}
+
+func (c *writeCountingClient) count() {
+	c.mu.Lock()
+	c.writes++
+	c.mu.Unlock()
+}
+
+func (c *writeCountingClient) writeCount() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.writes
+}
+
+func (c *writeCountingClient) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	c.count()
+	return c.Client.Patch(ctx, obj, patch, opts...)
+}
+
+func (c *writeCountingClient) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	c.count()
+	return c.Client.Update(ctx, obj, opts...)
+}
+
+func (c *writeCountingClient) Status() client.StatusWriter {
+	return &writeCountingStatusWriter{StatusWriter: c.Client.Status(), client: c}
+}
+
+type writeCountingStatusWriter struct {
+	client.StatusWriter
+	client *writeCountingClient
+}
+
+func (w *writeCountingStatusWriter) Patch(ctx context.Context, obj client.Object, patch client.Patch, opts ...client.PatchOption) error {
+	w.client.count()
+	return w.StatusWriter.Patch(ctx, obj, patch, opts...)
+}
+
+func (w *writeCountingStatusWriter) Update(ctx context.Context, obj client.Object, opts ...client.UpdateOption) error {
+	w.client.count()
+	return w.StatusWriter.Update(ctx, obj, opts...)
+}
+
+// BenchmarkScaleReconcile runs the actuator Update of scaleMachines machines of running
+// VMs, spread over the credentials secrets of two MachineSets, as the machine controller
+// does once the machines are provisioned. An operation is a reconcile of every machine.
+// The API server writes, the engine calls and the allocations are reported per reconcile
+// of a single machine.
+func BenchmarkScaleReconcile(b *testing.B) {
+	// the actuator logs every reconcile
+	flags := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(flags)
+	if err := flags.Set("logtostderr", "false"); err != nil {
+		b.Fatal(err)
+	}
+	klog.SetOutput(ioutil.Discard)
+
+	engine := newFakeEngine(b)
+	scheme := k8sruntime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+	if err := machinev1.AddToScheme(scheme); err != nil {
+		b.Fatal(err)
+	}
+
+	objs := []k8sruntime.Object{
+		credentialsSecret(engine, "ns", "creds-0"),
+		credentialsSecret(engine, "ns", "creds-1"),
+	}
+	machines := make([]*machinev1.Machine, scaleMachines)
+	for i := range machines {
+		spec, err := ovirtconfigv1.RawExtensionFromProviderSpec(&ovirtconfigv1.OvirtMachineProviderSpec{
+			ClusterId:         benchCluster,
+			TemplateName:      "rhcos",
+			CredentialsSecret: &corev1.LocalObjectReference{Name: fmt.Sprintf("creds-%d", i%2)},
+		})
+		if err != nil {
+			b.Fatal(err)
+		}
+		providerID := fmt.Sprintf("%svm-%d", ovirt.ProviderIDPrefix, i)
+		machines[i] = &machinev1.Machine{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      fmt.Sprintf("worker-%d", i),
+				UID:       types.UID(fmt.Sprintf("uid-%d", i)),
+			},
+			Spec: machinev1.MachineSpec{
+				ProviderID:   &providerID,
+				ProviderSpec: machinev1.ProviderSpec{Value: spec},
+			},
+		}
+		objs = append(objs, machines[i].DeepCopy())
+	}
+	c := &writeCountingClient{Client: fake.NewClientBuilder().WithScheme(scheme).WithRuntimeObjects(objs...).Build()}
+	actuator, err := NewActuator(ovirt.ActuatorParams{
+		Client:        c,
+		Scheme:        scheme,
+		EventRecorder: &record.FakeRecorder{},
+		// the infrastructure is fetched on every reconcile, it isn't rate limited here
+		Config: &rest.Config{Host: engine.URL, QPS: -1},
+	})
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	reconcile := func() {
+		for _, machine := range machines {
+			if err := actuator.Update(context.TODO(), machine); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+	// the first reconcile sets the provider ID, the addresses and the status of the machines
+	reconcile()
+
+	var before, after runtime.MemStats
+	writes, calls := c.writeCount(), engine.callCount()
+	runtime.ReadMemStats(&before)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		reconcile()
+	}
+	b.StopTimer()
+	runtime.ReadMemStats(&after)
+
+	reconciles := float64(b.N * scaleMachines)
+	b.ReportMetric(float64(c.writeCount()-writes)/reconciles, "writes/reconcile")
+	b.ReportMetric(float64(engine.callCount()-calls)/reconciles, "engine-calls/reconcile")
+	b.ReportMetric(float64(after.Mallocs-before.Mallocs)/reconciles, "allocs/reconcile")
+}
+
+// credentialsSecret returns the credentials secret of the fake engine
+func credentialsSecret(e *fakeEngine, namespace, name string) *corev1.Secret {
+	return &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Data: map[string][]byte{
+			"ovirt_url":      []byte(e.URL + engineAPIPath),
+			"ovirt_username": []byte("admin@internal"),
+			"ovirt_password": []byte("secret"),
+			"ovirt_insecure": []byte("true"),
+		},
+	}
+}
@@ -0,0 +1,56 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package machine
+
+import (
+	"context"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	k8serrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+)
+
+// NewMachineSelectingManager returns the manager to add the machine controller with. Its
+// client hides the machines not matching the selector. The machine controller starts its
+// reconcile by getting the machine, so it treats the hidden machines as gone and leaves
+// them to the provider deployment which selects them, e.g the canary of a new provider
+// version. The other controllers keep the client of mgr, which gets every machine.
+func NewMachineSelectingManager(mgr manager.Manager, selector labels.Selector) manager.Manager {
+	return &machineSelectingManager{
+		Manager: mgr,
+		client:  &machineSelectingClient{Client: mgr.GetClient(), selector: selector},
+	}
+}
+
+type machineSelectingManager struct {
+	manager.Manager
+	client client.Client
+}
+
+func (m *machineSelectingManager) GetClient() client.Client {
+	return m.client
+}
+
+// machineSelectingClient returns NotFound when getting a machine which doesn't match
+// the selector. The machines are still listed, so the counts over all the machines,
+// e.g the cluster quotas, aren't changed.
+type machineSelectingClient struct {
+	client.Client
+	selector labels.Selector
+}
+
+func (c *machineSelectingClient) Get(ctx context.Context, key client.ObjectKey, obj client.Object) error {
+	if err := c.Client.Get(ctx, key, obj); err != nil {
+		return err
+	}
+	if machine, ok := obj.(*machinev1.Machine); ok && !c.selector.Matches(labels.Set(machine.Labels)) {
+		return k8serrors.NewNotFound(schema.GroupResource{Group: machinev1.SchemeGroupVersion.Group, Resource: "machines"}, key.Name)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	// ConnectionPoolSize is the number of engine connections held by the connection pool
+	ConnectionPoolSize = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "ovirt_connection_pool_size",
+			Help: "Number of oVirt engine connections held by the connection pool",
+		},
+	)
+
+	// ConnectionPoolEvictions counts the connections evicted from the connection pool
+	ConnectionPoolEvictions = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "ovirt_connection_pool_evictions_total",
+			Help: "Number of oVirt engine connections evicted from the connection pool",
+		},
+		[]string{"reason"},
+	)
+
+	// MachineVCPUs is the number of virtual CPUs allocated to a machine VM
+	MachineVCPUs = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ovirt_machine_vcpus",
+			Help: "Number of virtual CPUs allocated to the machine VM",
+		},
+		MachineAllocationLabels,
+	)
+
+	// MachineMemoryBytes is the memory allocated to a machine VM
+	MachineMemoryBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ovirt_machine_memory_bytes",
+			Help: "Memory allocated to the machine VM in bytes",
+		},
+		MachineAllocationLabels,
+	)
+
+	// MachineDiskProvisionedBytes is the provisioned size of the disks of a machine VM
+	MachineDiskProvisionedBytes = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ovirt_machine_disk_provisioned_bytes",
+			Help: "Provisioned size of the machine VM disks in bytes",
+		},
+		MachineAllocationLabels,
+	)
+
+	// MissingPermits marks the required engine permits the user of a credentials secret is missing
+	MissingPermits = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "ovirt_missing_permits",
+			Help: "Required oVirt engine permits missing for the user of the credentials secret",
+		},
+		[]string{"secret", "permit"},
+	)
+
+	// MachineAllocationLabels are the labels of the machine allocation metrics
+	MachineAllocationLabels = []string{"namespace", "machine", "machineset", "cluster_id", "template"}
+)
+
+func init() {
+	metrics.Registry.MustRegister(
+		ConnectionPoolSize,
+		ConnectionPoolEvictions,
+		MachineVCPUs,
+		MachineMemoryBytes,
+		MachineDiskProvisionedBytes,
+		MissingPermits,
+	)
+}
@@ -0,0 +1,123 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"context"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	"github.com/prometheus/client_golang/prometheus"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// The provisioning phases the machines of a MachineSet are counted in
+const (
+	ProvisioningPhaseCloning    = "cloning"
+	ProvisioningPhaseStarting   = "starting"
+	ProvisioningPhaseAwaitingIP = "awaiting_ip"
+	ProvisioningPhaseFailed     = "failed"
+)
+
+const machineSetLabel = "machine.openshift.io/cluster-api-machineset"
+
+var provisioningMachinesDesc = prometheus.NewDesc(
+	"ovirt_machineset_provisioning_machines",
+	"Number of machines of the MachineSet in each provisioning phase",
+	[]string{"namespace", "machineset", "phase"},
+	nil,
+)
+
+// ProvisioningCollector counts the machines of each MachineSet which aren't provisioned
+// yet by provisioning phase, giving a rollup view of large scale operations. The
+// machines are listed from the manager cache on every scrape.
+type ProvisioningCollector struct {
+	client client.Client
+}
+
+// NewProvisioningCollector returns a collector listing the machines with the client
+func NewProvisioningCollector(c client.Client) *ProvisioningCollector {
+	return &ProvisioningCollector{client: c}
+}
+
+// Describe implements prometheus.Collector
+func (c *ProvisioningCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- provisioningMachinesDesc
+}
+
+// Collect implements prometheus.Collector
+func (c *ProvisioningCollector) Collect(ch chan<- prometheus.Metric) {
+	machines := &machinev1.MachineList{}
+	if err := c.client.List(context.TODO(), machines, client.HasLabels{machineSetLabel}); err != nil {
+		klog.Warningf("Failed listing the machines, skipping the provisioning metrics: %v", err)
+		return
+	}
+	type machineSetKey struct{ namespace, name string }
+	counts := make(map[machineSetKey]map[string]int)
+	for i := range machines.Items {
+		machine := &machines.Items[i]
+		phase := ProvisioningPhase(machine)
+		if phase == "" {
+			continue
+		}
+		key := machineSetKey{machine.Namespace, machine.Labels[machineSetLabel]}
+		if counts[key] == nil {
+			counts[key] = make(map[string]int)
+		}
+		counts[key][phase]++
+	}
+	for key, phases := range counts {
+		for _, phase := range []string{ProvisioningPhaseCloning, ProvisioningPhaseStarting,
+			ProvisioningPhaseAwaitingIP, ProvisioningPhaseFailed} {
+			ch <- prometheus.MustNewConstMetric(provisioningMachinesDesc, prometheus.GaugeValue,
+				float64(phases[phase]), key.namespace, key.name, phase)
+		}
+	}
+}
+
+// ProvisioningPhase returns the provisioning phase of the machine, or an empty string
+// if the machine has a node, is deleted, or its creation wasn't recorded by this provider
+func ProvisioningPhase(machine *machinev1.Machine) string {
+	if machine.DeletionTimestamp != nil || machine.Status.NodeRef != nil {
+		return ""
+	}
+	if machine.Status.ErrorReason != nil {
+		return ProvisioningPhaseFailed
+	}
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil || providerStatus.CloneStartTime == nil {
+		return ""
+	}
+	state := ""
+	if providerStatus.InstanceState != nil {
+		state = *providerStatus.InstanceState
+	}
+	switch providerStatus.CreatePhase {
+	case "", ovirtconfigv1.CreatePhaseVMCreated:
+		// the disks are ready once the template disks are cloned
+		return ProvisioningPhaseCloning
+	}
+	switch {
+	case providerStatus.CreatePhase != ovirtconfigv1.CreatePhaseVMStarted || state != string(ovirtsdk.VMSTATUS_UP):
+		return ProvisioningPhaseStarting
+	case !hasInternalIP(machine):
+		return ProvisioningPhaseAwaitingIP
+	}
+	return ""
+}
+
+func hasInternalIP(machine *machinev1.Machine) bool {
+	for _, address := range machine.Status.Addresses {
+		if address.Type == corev1.NodeInternalIP {
+			return true
+		}
+	}
+	return false
+}
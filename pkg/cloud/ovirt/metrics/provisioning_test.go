@@ -0,0 +1,73 @@
+//go:build unit
+// +build unit
+
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package metrics
+
+import (
+	"testing"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	ovirtsdk "github.com/ovirt/go-ovirt"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+func TestProvisioningPhase(t *testing.T) {
+	now := metav1.Now()
+	up := string(ovirtsdk.VMSTATUS_UP)
+	down := string(ovirtsdk.VMSTATUS_DOWN)
+	createError := machinev1.CreateMachineError
+	internalIP := []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: "192.168.1.10"}}
+
+	// machine returns a machine with the provider status, if cloned, and the addresses
+	machine := func(cloned bool, phase ovirtconfigv1.CreatePhase, state *string, addresses []corev1.NodeAddress) *machinev1.Machine {
+		status := &ovirtconfigv1.OvirtMachineProviderStatus{CreatePhase: phase, InstanceState: state}
+		if cloned {
+			status.CloneStartTime = &now
+		}
+		raw, err := ovirtconfigv1.RawExtensionFromProviderStatus(status)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return &machinev1.Machine{
+			Status: machinev1.MachineStatus{ProviderStatus: raw, Addresses: addresses},
+		}
+	}
+	withNode := machine(true, ovirtconfigv1.CreatePhaseVMStarted, &up, internalIP)
+	withNode.Status.NodeRef = &corev1.ObjectReference{Name: "worker-0"}
+	deleted := machine(true, ovirtconfigv1.CreatePhaseVMCreated, nil, nil)
+	deleted.DeletionTimestamp = &now
+	failed := machine(false, "", nil, nil)
+	failed.Status.ErrorReason = &createError
+
+	for _, tc := range []struct {
+		name    string
+		machine *machinev1.Machine
+		want    string
+	}{
+		{"with a node", withNode, ""},
+		{"deleted", deleted, ""},
+		{"failed", failed, ProvisioningPhaseFailed},
+		{"not created by this provider", machine(false, "", nil, nil), ""},
+		{"no provider status", &machinev1.Machine{}, ""},
+		{"cloning before the VM is created", machine(true, "", nil, nil), ProvisioningPhaseCloning},
+		{"cloning the template disks", machine(true, ovirtconfigv1.CreatePhaseVMCreated, &down, nil), ProvisioningPhaseCloning},
+		{"configuring the VM", machine(true, ovirtconfigv1.CreatePhaseNICsConfigured, &down, nil), ProvisioningPhaseStarting},
+		{"VM started but not up", machine(true, ovirtconfigv1.CreatePhaseVMStarted, &down, nil), ProvisioningPhaseStarting},
+		{"VM up without an address", machine(true, ovirtconfigv1.CreatePhaseVMStarted, &up, nil), ProvisioningPhaseAwaitingIP},
+		{"VM up with an address", machine(true, ovirtconfigv1.CreatePhaseVMStarted, &up, internalIP), ""},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := ProvisioningPhase(tc.machine); got != tc.want {
+				t.Errorf("ProvisioningPhase() = %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
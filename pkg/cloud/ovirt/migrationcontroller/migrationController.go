@@ -0,0 +1,206 @@
+package migrationcontroller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/klog/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+)
+
+const (
+	// MigratedAnnotation records the migration version a machine was migrated to
+	MigratedAnnotation = "ovirt.machine.openshift.io/migrated"
+	// MigrationVersion is the current migration version, it is raised whenever the
+	// migration back-fills something new, so the machines are migrated again
+	MigrationVersion = "2"
+)
+
+var _ reconcile.Reconciler = &migrationReconciler{}
+
+// migrationReconciler back-fills onto the machines created by older provider versions,
+// and onto their VMs, what the current version records when it creates a machine, so
+// an upgrade doesn't leave a mixed fleet. Every machine is migrated once per version.
+type migrationReconciler struct {
+	log         logr.Logger
+	client      client.Client
+	connections *clients.ConnectionPool
+}
+
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machines,verbs=get;list;watch;patch
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machines/status,verbs=patch
+
+func (r *migrationReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	machine := &machinev1.Machine{}
+	if err := r.client.Get(ctx, request.NamespacedName, machine); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("error getting machine %s: %v", request.NamespacedName, err)
+	}
+	if machine.DeletionTimestamp != nil || machine.Annotations[MigratedAnnotation] == MigrationVersion {
+		return reconcile.Result{}, nil
+	}
+	providerStatus, err := ovirtconfigv1.ProviderStatusFromRawExtension(machine.Status.ProviderStatus)
+	if err != nil {
+		r.log.Info("Skipping the migration of a machine with an invalid provider status", "Machine",
+			request.NamespacedName, "error", err.Error())
+		return reconcile.Result{}, nil
+	}
+	// the current version records the clone start time when it starts creating a machine,
+	// such a machine without a provider ID is still being created by the actuator, it is
+	// migrated once its creation sets the provider ID
+	if (machine.Spec.ProviderID == nil || *machine.Spec.ProviderID == "") && providerStatus.CloneStartTime != nil {
+		return reconcile.Result{}, nil
+	}
+	r.log.Info("Migrating", "Machine", request.NamespacedName, "version", MigrationVersion)
+
+	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		// the machine actuator reports the invalid provider spec
+		r.log.Info("Skipping the migration of a machine with an invalid provider spec", "Machine",
+			request.NamespacedName, "error", err.Error())
+		return reconcile.Result{}, nil
+	}
+	connection, err := r.connections.Get(machine.Namespace, providerSpec.CredentialsSecret.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to create connection to oVirt API: %v", err)
+	}
+	defer r.connections.Release(connection)
+
+	machineService, err := clients.NewInstanceServiceFromMachine(machine, connection)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	// the VM is fetched by the provider ID first, it may have been renamed in the engine
+	instance, err := machineService.GetVm(*machine)
+	if clients.IsOwnershipConflict(err) {
+		// the machine actuator reports the conflict
+		r.log.Info("Skipping the migration of a machine whose VM is owned by another machine", "Machine",
+			request.NamespacedName, "error", err.Error())
+		return reconcile.Result{}, nil
+	}
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed fetching the VM of machine %s: %v", request.NamespacedName, err)
+	}
+
+	original := machine.DeepCopy()
+	if machine.Annotations == nil {
+		machine.Annotations = make(map[string]string)
+	}
+	// a machine without a VM was deleted or wasn't created yet, the current version creates it
+	if instance != nil {
+		if err := machineService.MigrateInstance(machine, instance.Vm); err != nil {
+			return reconcile.Result{}, err
+		}
+		migrateProviderID(machine, instance)
+		if err := migrateProviderStatus(machine, providerStatus, instance); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	machine.Annotations[MigratedAnnotation] = MigrationVersion
+
+	// the status is discarded and returned fresh from the DB by the machine resource
+	// patch, save it for the status sub-resource patch
+	status := machine.Status.DeepCopy()
+	if err := r.client.Patch(ctx, machine, client.MergeFrom(original)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed patching the migrated machine %s: %v", request.NamespacedName, err)
+	}
+	if instance == nil {
+		return reconcile.Result{}, nil
+	}
+	statusBase := machine.DeepCopy()
+	machine.Status = *status
+	if err := r.client.Status().Patch(ctx, machine, client.MergeFrom(statusBase)); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed patching the status of the migrated machine %s: %v", request.NamespacedName, err)
+	}
+	return reconcile.Result{}, nil
+}
+
+// migrateProviderID back-fills the provider ID and the VM ID annotation of the machine,
+// as the actuator sets them once the VM of a machine is created
+func migrateProviderID(machine *machinev1.Machine, instance *clients.Instance) {
+	id := instance.MustId()
+	if machine.Spec.ProviderID == nil || *machine.Spec.ProviderID == "" {
+		providerID := ovirt.ProviderIDPrefix + id
+		machine.Spec.ProviderID = &providerID
+	}
+	if machine.Annotations[ovirt.OvirtIdAnnotationKey] == "" {
+		machine.Annotations[ovirt.OvirtIdAnnotationKey] = id
+	}
+}
+
+// migrateProviderStatus back-fills the instance ID, the instance state and the
+// MachineCreated condition of the provider status, as the actuator records them once
+// the VM of a machine is created. What the status already records is kept.
+func migrateProviderStatus(machine *machinev1.Machine, providerStatus *ovirtconfigv1.OvirtMachineProviderStatus, instance *clients.Instance) error {
+	if providerStatus.InstanceID == nil {
+		id := instance.MustId()
+		providerStatus.InstanceID = &id
+	}
+	if providerStatus.InstanceState == nil {
+		state := string(instance.MustStatus())
+		providerStatus.InstanceState = &state
+	}
+	created := false
+	for _, c := range providerStatus.Conditions {
+		if c.Type == ovirtconfigv1.MachineCreated {
+			created = true
+			break
+		}
+	}
+	if !created {
+		now := metav1.Now()
+		providerStatus.Conditions = append(providerStatus.Conditions, ovirtconfigv1.OvirtMachineProviderCondition{
+			Type:               ovirtconfigv1.MachineCreated,
+			Status:             corev1.ConditionTrue,
+			Reason:             "MachineCreateSucceeded",
+			Message:            "Machine successfully created",
+			LastProbeTime:      now,
+			LastTransitionTime: now,
+		})
+	}
+	rawExtension, err := ovirtconfigv1.RawExtensionFromProviderStatus(providerStatus)
+	if err != nil {
+		return err
+	}
+	machine.Status.ProviderStatus = rawExtension
+	return nil
+}
+
+// Add registers the migration controller, mgr may be a manager selecting the machines,
+// as the machine controller, so only the machines of this deployment are migrated
+func Add(mgr manager.Manager, opts manager.Options, connections *clients.ConnectionPool) error {
+	reconciler := NewMigrationReconciler(mgr, connections)
+
+	c, err := controller.New("migration-controller", mgr, controller.Options{Reconciler: reconciler})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &machinev1.Machine{}}, &handler.EnqueueRequestForObject{})
+}
+
+func NewMigrationReconciler(mgr manager.Manager, connections *clients.ConnectionPool) *migrationReconciler {
+	log.SetLogger(klogr.New())
+	return &migrationReconciler{
+		log:         log.Log.WithName("controllers").WithName("migration-reconciler"),
+		client:      mgr.GetClient(),
+		connections: connections,
+	}
+}
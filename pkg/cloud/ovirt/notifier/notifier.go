@@ -0,0 +1,177 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package notifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/workqueue"
+	"k8s.io/klog"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Transition is a machine lifecycle transition reported to the webhook
+type Transition string
+
+const (
+	// MachineCreated is sent once the machine VM is created
+	MachineCreated Transition = "created"
+	// MachineRunning is sent when the machine VM comes up
+	MachineRunning Transition = "running"
+	// MachineDeleted is sent once the machine VM is removed
+	MachineDeleted Transition = "deleted"
+)
+
+const (
+	requestTimeout = 10 * time.Second
+	// maxAttempts bounds the deliveries of an event the webhook keeps failing
+	maxAttempts = 10
+)
+
+// Event is the JSON payload posted to the webhook
+type Event struct {
+	Transition Transition           `json:"transition"`
+	Timestamp  time.Time            `json:"timestamp"`
+	Namespace  string               `json:"namespace"`
+	Machine    string               `json:"machine"`
+	MachineSet string               `json:"machineSet,omitempty"`
+	ClusterID  string               `json:"clusterId,omitempty"`
+	VMID       string               `json:"vmId,omitempty"`
+	VMName     string               `json:"vmName,omitempty"`
+	Addresses  []corev1.NodeAddress `json:"addresses,omitempty"`
+	// FQDN is the fully qualified domain name the guest agent reported, so the
+	// external DNS records of a deleted machine can be cleaned up
+	FQDN string `json:"fqdn,omitempty"`
+}
+
+// Notifier posts the machine lifecycle transitions to an external webhook, e.g a
+// CMDB or an IPAM system tracking the VM inventory. A nil Notifier is a no-op.
+// The events are queued and posted by Start, a failed post is retried with an
+// exponential backoff up to maxAttempts times.
+type Notifier struct {
+	url        string
+	client     client.Client
+	secret     types.NamespacedName
+	httpClient *http.Client
+	queue      workqueue.RateLimitingInterface
+}
+
+// New returns a notifier posting to url. If secretName isn't empty, the requests are
+// authenticated with the secret, by a bearer token from its "token" key, or by basic
+// auth from its "username" and "password" keys.
+func New(url string, client client.Client, secretNamespace, secretName string) *Notifier {
+	if url == "" {
+		return nil
+	}
+	return &Notifier{
+		url:        url,
+		client:     client,
+		secret:     types.NamespacedName{Namespace: secretNamespace, Name: secretName},
+		httpClient: &http.Client{Timeout: requestTimeout},
+		queue: workqueue.NewNamedRateLimitingQueue(
+			workqueue.NewItemExponentialFailureRateLimiter(time.Second, 5*time.Minute), "lifecycle-webhook"),
+	}
+}
+
+// Notify queues the event for the webhook. It doesn't wait for the delivery, failures
+// are logged and don't fail the reconcile, the webhook is informational.
+func (n *Notifier) Notify(_ context.Context, event Event) {
+	if n == nil {
+		return
+	}
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now().UTC()
+	}
+	n.queue.Add(&event)
+}
+
+// Start posts the queued events until the context is done. It implements
+// manager.Runnable, so the notifier is run by the controller manager.
+func (n *Notifier) Start(ctx context.Context) error {
+	if n == nil {
+		return nil
+	}
+	go func() {
+		<-ctx.Done()
+		n.queue.ShutDown()
+	}()
+	for n.processNext(ctx) {
+	}
+	return nil
+}
+
+func (n *Notifier) processNext(ctx context.Context) bool {
+	item, shutdown := n.queue.Get()
+	if shutdown {
+		return false
+	}
+	defer n.queue.Done(item)
+
+	event := item.(*Event)
+	err := n.post(ctx, *event)
+	if err == nil {
+		n.queue.Forget(item)
+		return true
+	}
+	if n.queue.NumRequeues(item) < maxAttempts-1 {
+		klog.V(3).Infof("Failed notifying the lifecycle webhook of machine %s %s, retrying: %v",
+			event.Machine, event.Transition, err)
+		n.queue.AddRateLimited(item)
+		return true
+	}
+	klog.Warningf("Failed notifying the lifecycle webhook of machine %s %s after %d attempts, dropping it: %v",
+		event.Machine, event.Transition, maxAttempts, err)
+	n.queue.Forget(item)
+	return true
+}
+
+func (n *Notifier) post(ctx context.Context, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, n.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	if err := n.authenticate(ctx, req); err != nil {
+		return err
+	}
+	res, err := n.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responded with %s", res.Status)
+	}
+	return nil
+}
+
+func (n *Notifier) authenticate(ctx context.Context, req *http.Request) error {
+	if n.secret.Name == "" {
+		return nil
+	}
+	var secret corev1.Secret
+	if err := n.client.Get(ctx, n.secret, &secret); err != nil {
+		return fmt.Errorf("failed getting the webhook secret %s: %v", n.secret, err)
+	}
+	if token, ok := secret.Data["token"]; ok {
+		req.Header.Set("Authorization", "Bearer "+string(token))
+		return nil
+	}
+	req.SetBasicAuth(string(secret.Data["username"]), string(secret.Data["password"]))
+	return nil
+}
@@ -0,0 +1,111 @@
+package prewarmcontroller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/klog/klogr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+	"sigs.k8s.io/controller-runtime/pkg/source"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
+)
+
+const (
+	// PrewarmTemplateAnnotation makes the template of a MachineSet copied to the storage
+	// domains its machines are placed on, before the machines are created
+	PrewarmTemplateAnnotation = "ovirt.machine.openshift.io/prewarm-template"
+	// copyPollInterval is the time to wait before checking the template disk copies again
+	copyPollInterval = 30 * time.Second
+)
+
+var _ reconcile.Reconciler = &prewarmReconciler{}
+
+// prewarmReconciler copies the template disks of the annotated MachineSets to the
+// storage domains of their machines, so a rollout creates thin disks on every domain
+type prewarmReconciler struct {
+	log           logr.Logger
+	client        client.Client
+	eventRecorder record.EventRecorder
+	connections   *clients.ConnectionPool
+}
+
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machinesets,verbs=get;list;watch
+// +kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+
+func (r *prewarmReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
+	machineSet := &machinev1.MachineSet{}
+	if err := r.client.Get(ctx, request.NamespacedName, machineSet); err != nil {
+		if errors.IsNotFound(err) {
+			return reconcile.Result{}, nil
+		}
+		return reconcile.Result{}, fmt.Errorf("error getting machineset %s: %v", request.NamespacedName, err)
+	}
+	if _, ok := machineSet.Annotations[PrewarmTemplateAnnotation]; !ok || machineSet.DeletionTimestamp != nil {
+		return reconcile.Result{}, nil
+	}
+	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machineSet.Spec.Template.Spec.ProviderSpec.Value)
+	if err != nil {
+		r.log.Info("Skipping the template of a MachineSet with an invalid provider spec", "MachineSet",
+			request.NamespacedName, "error", err.Error())
+		return reconcile.Result{}, nil
+	}
+	connection, err := r.connections.Get(machineSet.Namespace, providerSpec.CredentialsSecret.Name)
+	if err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to create connection to oVirt API: %v", err)
+	}
+	defer r.connections.Release(connection)
+
+	machineService := &clients.InstanceService{Connection: connection, ClusterId: providerSpec.ClusterId}
+	if err := machineService.ResolveTemplate(providerSpec); err != nil {
+		return reconcile.Result{}, err
+	}
+	started, err := machineService.PrewarmTemplate(providerSpec)
+	if len(started) > 0 {
+		r.eventRecorder.Eventf(machineSet, corev1.EventTypeNormal, "PrewarmingTemplate",
+			"Copying the template disks %s to the storage domains of the machines", strings.Join(started, ", "))
+	}
+	if clients.IsInProgress(err) {
+		r.log.Info("Waiting for the template disk copies", "MachineSet", request.NamespacedName)
+		return reconcile.Result{RequeueAfter: copyPollInterval}, nil
+	}
+	if err != nil {
+		r.eventRecorder.Eventf(machineSet, corev1.EventTypeWarning, "PrewarmTemplateFailed", "%v", err)
+		return reconcile.Result{}, err
+	}
+	return reconcile.Result{}, nil
+}
+
+func Add(mgr manager.Manager, opts manager.Options, connections *clients.ConnectionPool) error {
+	reconciler := NewPrewarmReconciler(mgr, connections)
+
+	c, err := controller.New("prewarm-controller", mgr, controller.Options{Reconciler: reconciler})
+	if err != nil {
+		return err
+	}
+
+	return c.Watch(&source.Kind{Type: &machinev1.MachineSet{}}, &handler.EnqueueRequestForObject{})
+}
+
+func NewPrewarmReconciler(mgr manager.Manager, connections *clients.ConnectionPool) *prewarmReconciler {
+	log.SetLogger(klogr.New())
+	return &prewarmReconciler{
+		log:           log.Log.WithName("controllers").WithName("prewarm-reconciler"),
+		client:        mgr.GetClient(),
+		eventRecorder: mgr.GetEventRecorderFor("ovirtprovider"),
+		connections:   connections,
+	}
+}
@@ -4,12 +4,16 @@ import (
 	"context"
 	"fmt"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt"
+	"strings"
 	"time"
 
 	"github.com/go-logr/logr"
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
 	ovirtsdk "github.com/ovirt/go-ovirt"
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/cache"
 	"k8s.io/klog/klogr"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller"
@@ -19,6 +23,7 @@ import (
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 	"sigs.k8s.io/controller-runtime/pkg/source"
 
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
 	"github.com/openshift/cluster-api-provider-ovirt/pkg/cloud/ovirt/clients"
 )
 
@@ -26,6 +31,9 @@ const (
 	RETRY_INTERVAL_VM_DOWN = 60 * time.Second
 	NAMESPACE              = "openshift-machine-api"
 	CREDENTIALS_SECRET     = "ovirt-credentials"
+
+	// machineAnnotation is set on the nodes by the machine API with the namespace/name of their machine
+	machineAnnotation = "machine.openshift.io/machine"
 )
 
 var _ reconcile.Reconciler = &providerIDReconciler{}
@@ -34,10 +42,15 @@ type providerIDReconciler struct {
 	log                  logr.Logger
 	client               client.Client
 	listNodesByFieldFunc func(key, value string) ([]corev1.Node, error)
-	fetchProviderIDFunc  func(string) (string, error)
-	ovirtApi             *ovirtsdk.Connection
+	fetchProviderIDFunc  func(*corev1.Node) (string, error)
+	connections          *clients.ConnectionPool
+	// credentialsSecret is the secret of the engine credentials the nodes are checked with
+	credentialsSecret types.NamespacedName
 }
 
+// +kubebuilder:rbac:groups="",resources=nodes,verbs=get;list;watch;update;patch;delete
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machines,verbs=get;list;watch
+
 func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.Request) (reconcile.Result, error) {
 	r.log.Info("Reconciling", "Node", request.NamespacedName)
 
@@ -54,7 +67,7 @@ func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.
 		// Error reading the object - requeue the request.
 		return reconcile.Result{}, fmt.Errorf("error getting node: %v", err)
 	}
-	id, err := r.fetchProviderIDFunc(node.Name)
+	id, err := r.fetchProviderIDFunc(&node)
 	if err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed getting VM from oVirt: %v", err)
 	}
@@ -67,8 +80,12 @@ func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.
 	}
 	if node.Spec.ProviderID != "" {
 		// Node exist and providerID is set
-		c, err := r.getConnection(NAMESPACE, CREDENTIALS_SECRET)
+		c, err := r.connections.Get(r.credentialsSecret.Namespace, r.credentialsSecret.Name)
+		if err != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to create connection to oVirt API: %v", err)
+		}
 		vmResponse, err := c.SystemService().VmsService().VmService(id).Get().Send()
+		r.connections.Release(c)
 		if err != nil {
 			return reconcile.Result{}, fmt.Errorf("failed getting VM from oVirt: %v", err)
 		}
@@ -79,8 +96,11 @@ func (r *providerIDReconciler) Reconcile(ctx context.Context, request reconcile.
 		}
 	} else {
 		r.log.Info("spec.ProviderID is empty, fetching from ovirt", "node", request.NamespacedName)
+		// merge patch the provider ID only, so the concurrent writes of the kubelet and
+		// of the machine controllers to the node don't conflict with it
+		base := node.DeepCopy()
 		node.Spec.ProviderID = ovirt.ProviderIDPrefix + id
-		err = r.client.Update(ctx, &node)
+		err = r.client.Patch(ctx, &node, client.MergeFrom(base))
 		if err != nil {
 			return reconcile.Result{}, fmt.Errorf("failed updating node %s: %v", node.Name, err)
 		}
@@ -95,12 +115,41 @@ func deleteNode(ctx context.Context, client client.Client, node *corev1.Node) (r
 	return reconcile.Result{}, nil
 }
 
-func (r *providerIDReconciler) fetchOvirtVmID(nodeName string) (string, error) {
-	c, err := r.getConnection(NAMESPACE, CREDENTIALS_SECRET)
+func (r *providerIDReconciler) fetchOvirtVmID(node *corev1.Node) (string, error) {
+	nodeName := node.Name
+	c, err := r.connections.Get(r.credentialsSecret.Namespace, r.credentialsSecret.Name)
+	if err != nil {
+		return "", err
+	}
+	defer r.connections.Release(c)
+	if node.Spec.ProviderID != "" {
+		// the VM is looked up by the ID, it may have been renamed in the engine
+		id := strings.TrimPrefix(node.Spec.ProviderID, ovirt.ProviderIDPrefix)
+		_, err := c.SystemService().VmsService().VmService(id).Get().Send()
+		if _, notFound := err.(*ovirtsdk.NotFoundError); notFound {
+			return "", nil
+		}
+		if err != nil {
+			return "", err
+		}
+		return id, nil
+	}
+	machine, err := r.nodeMachine(node)
 	if err != nil {
 		return "", err
 	}
-	send, err := c.SystemService().VmsService().List().Search(fmt.Sprintf("name=%s", nodeName)).Send()
+	// the search is restricted to the oVirt cluster of the machine, as the actuator does
+	clusterID := ""
+	if machine != nil {
+		if providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value); err == nil {
+			clusterID = providerSpec.ClusterId
+		}
+	}
+	query, err := clients.VMNameSearch(c, nodeName, clusterID)
+	if err != nil {
+		return "", err
+	}
+	send, err := c.SystemService().VmsService().List().Search(query).Send()
 	if err != nil {
 		r.log.Error(err, "Error occurred will searching VM", "VM name", nodeName)
 		return "", err
@@ -111,11 +160,51 @@ func (r *providerIDReconciler) fetchOvirtVmID(nodeName string) (string, error) {
 	} else if l == 0 {
 		return "", nil
 	}
+	if err := verifyOwner(node, machine, vms[0]); err != nil {
+		return "", err
+	}
 	return vms[0].MustId(), nil
 }
 
-func Add(mgr manager.Manager, opts manager.Options) error {
-	reconciler, err := NewProviderIDReconciler(mgr)
+// nodeMachine returns the machine of the node, or nil if the node has no machine
+func (r *providerIDReconciler) nodeMachine(node *corev1.Node) (*machinev1.Machine, error) {
+	machineKey := node.Annotations[machineAnnotation]
+	if machineKey == "" {
+		return nil, nil
+	}
+	namespace, name, err := cache.SplitMetaNamespaceKey(machineKey)
+	if err != nil {
+		return nil, err
+	}
+	machine := &machinev1.Machine{}
+	if err := r.client.Get(context.TODO(), types.NamespacedName{Namespace: namespace, Name: name}, machine); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return machine, nil
+}
+
+// verifyOwner returns an error if the VM records an owning machine other than the
+// machine of the node
+func verifyOwner(node *corev1.Node, machine *machinev1.Machine, vm *ovirtsdk.Vm) error {
+	owner := clients.MachineUID(vm)
+	if owner == "" || machine == nil {
+		return nil
+	}
+	if machine.UID != owner {
+		return fmt.Errorf("VM %s is owned by machine UID %s, not by the machine %s/%s of node %s",
+			vm.MustId(), owner, machine.Namespace, machine.Name, node.Name)
+	}
+	return nil
+}
+
+// Add registers the controller, which checks the nodes with the engine credentials
+// of the secret, e.g NAMESPACE/CREDENTIALS_SECRET of the provider running in the cluster,
+// over the connections of the pool shared with the machine actuator
+func Add(mgr manager.Manager, opts manager.Options, credentialsSecret types.NamespacedName, connections *clients.ConnectionPool) error {
+	reconciler, err := NewProviderIDReconciler(mgr, credentialsSecret, connections)
 
 	if err != nil {
 		return fmt.Errorf("error building reconciler: %v", err)
@@ -135,43 +224,18 @@ func Add(mgr manager.Manager, opts manager.Options) error {
 	return nil
 }
 
-func NewProviderIDReconciler(mgr manager.Manager) (*providerIDReconciler, error) {
+func NewProviderIDReconciler(
+	mgr manager.Manager,
+	credentialsSecret types.NamespacedName,
+	connections *clients.ConnectionPool) (*providerIDReconciler, error) {
+
 	log.SetLogger(klogr.New())
 	r := providerIDReconciler{
-		log:    log.Log.WithName("controllers").WithName("providerID-reconciler"),
-		client: mgr.GetClient(),
+		log:               log.Log.WithName("controllers").WithName("providerID-reconciler"),
+		client:            mgr.GetClient(),
+		connections:       connections,
+		credentialsSecret: credentialsSecret,
 	}
 	r.fetchProviderIDFunc = r.fetchOvirtVmID
 	return &r, nil
 }
-
-func (r *providerIDReconciler) getConnection(namespace, secretName string) (*ovirtsdk.Connection, error) {
-	var err error
-	if r.ovirtApi == nil || r.ovirtApi.Test() != nil {
-		// session expired or some other error, re-login.
-		r.ovirtApi, err = createApiConnection(r.client, namespace, secretName)
-	}
-	return r.ovirtApi, err
-}
-
-//createApiConnection returns a a client to oVirt's API endpoint
-func createApiConnection(client client.Client, namespace string, secretName string) (*ovirtsdk.Connection, error) {
-	creds, err := clients.GetCredentialsSecret(client, namespace, secretName)
-
-	if err != nil {
-		return nil, fmt.Errorf("failed getting credentials for namespace %s, %s", namespace, err)
-	}
-
-	connection, err := ovirtsdk.NewConnectionBuilder().
-		URL(creds.URL).
-		Username(creds.Username).
-		Password(creds.Password).
-		CAFile(creds.CAFile).
-		Insecure(creds.Insecure).
-		Build()
-	if err != nil {
-		return nil, err
-	}
-
-	return connection, nil
-}
@@ -17,9 +17,12 @@ limitations under the License.
 package ovirt
 
 import (
+	"time"
+
 	"github.com/openshift/machine-api-operator/pkg/generated/clientset/versioned/typed/machine/v1beta1"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/record"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
@@ -37,4 +40,51 @@ type ActuatorParams struct {
 	Scheme         *runtime.Scheme
 	MachinesClient v1beta1.MachineV1beta1Interface
 	EventRecorder  record.EventRecorder
+	// Config is the config of the cluster the machines belong to, e.g read from the
+	// --kubeconfig of a provider running outside of the cluster. If nil, the config is
+	// loaded the controller-runtime way.
+	Config *rest.Config
+
+	// ConnectionPoolSize is the maximum number of engine connections kept open
+	ConnectionPoolSize int
+	// ConnectionIdleTimeout is the time an unused engine connection is kept open
+	ConnectionIdleTimeout time.Duration
+
+	// LifecycleWebhookURL is the URL the machine lifecycle transitions are posted to
+	LifecycleWebhookURL string
+	// LifecycleWebhookSecret is the namespace/name of the secret authenticating the webhook requests
+	LifecycleWebhookSecret string
+
+	// StorageOvercommitThreshold is the percentage of the free space of a storage domain
+	// the OS disks of the machines a MachineSet still creates may take before a warning
+	// event is emitted on the MachineSet. If 0, the storage isn't checked.
+	StorageOvercommitThreshold int
+
+	// EvacuateBeforeDelete makes the deletion of a machine wait for its node to be
+	// drained, and release the VM from its enforcing affinity groups before stopping it
+	EvacuateBeforeDelete bool
+
+	// ShutdownTimeout is how long the guest of a deleted machine may take to shut down
+	// gracefully from the first shutdown request before its VM is powered off, unless
+	// its provider spec sets another timeout. If 0, the VM is powered off right away.
+	ShutdownTimeout time.Duration
+
+	// CreateTimeout is the time the template disks of a machine may take to be cloned
+	// before its creation fails. If 0, clients.DefaultCloneTimeout is used.
+	CreateTimeout time.Duration
+	// StartTimeout is the time a started VM may take to come up before its machine is
+	// moved to the Failed phase. If 0, the VM is waited for.
+	StartTimeout time.Duration
+	// DiskExtensionTimeout is the time the extension of the OS disk may take. If 0,
+	// clients.DefaultDiskExtensionTimeout is used.
+	DiskExtensionTimeout time.Duration
+	// DeleteTimeout is the time the removal of a VM may take before it is forced, as
+	// with the force-delete annotation, counted from the first shutdown request.
+	// If 0, the removal isn't forced.
+	DeleteTimeout time.Duration
+
+	// ClusterMachineQuota is the maximum number of machines per oVirt cluster ID.
+	// A machine isn't created in a cluster holding its maximum. Clusters which
+	// aren't listed have no quota.
+	ClusterMachineQuota map[string]int
 }
@@ -0,0 +1,104 @@
+/*
+Copyright oVirt Authors
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	machinev1 "github.com/openshift/machine-api-operator/pkg/apis/machine/v1beta1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	ovirtconfigv1 "github.com/openshift/cluster-api-provider-ovirt/pkg/apis/ovirtprovider/v1beta1"
+)
+
+// DefaultingPath is the path the machine defaulting webhook is served at
+const DefaultingPath = "/mutate-machine-openshift-io-v1beta1-machine-ovirt"
+
+// machineSetLabel names the MachineSet of a machine
+const machineSetLabel = "machine.openshift.io/cluster-api-machineset"
+
+// +kubebuilder:rbac:groups=ovirtproviderconfig.machine.openshift.io,resources=ovirtmachineproviderdefaults,verbs=get;list;watch
+// +kubebuilder:rbac:groups=machine.openshift.io,resources=machinesets,verbs=get;list;watch
+
+// MachineDefaulter merges the OvirtMachineProviderDefaults selected by a machine
+// into its provider spec. The defaults are named by the DefaultsAnnotation of the
+// machine, or else of its MachineSet, and fall back to DefaultsName.
+type MachineDefaulter struct {
+	client  client.Client
+	decoder *admission.Decoder
+}
+
+// NewMachineDefaulter returns a defaulting webhook handler reading the defaults with the client
+func NewMachineDefaulter(client client.Client) *MachineDefaulter {
+	return &MachineDefaulter{client: client}
+}
+
+// InjectDecoder implements admission.DecoderInjector
+func (d *MachineDefaulter) InjectDecoder(decoder *admission.Decoder) error {
+	d.decoder = decoder
+	return nil
+}
+
+// Handle sets the default values on the fields the machine provider spec doesn't set
+func (d *MachineDefaulter) Handle(ctx context.Context, req admission.Request) admission.Response {
+	machine := &machinev1.Machine{}
+	if err := d.decoder.Decode(req, machine); err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+
+	name, err := d.defaultsName(ctx, machine)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	defaults := &ovirtconfigv1.OvirtMachineProviderDefaults{}
+	if err := d.client.Get(ctx, types.NamespacedName{Name: name}, defaults); err != nil {
+		if apierrors.IsNotFound(err) {
+			return admission.Allowed("no provider defaults")
+		}
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+
+	providerSpec, err := ovirtconfigv1.ProviderSpecFromRawExtension(machine.Spec.ProviderSpec.Value)
+	if err != nil {
+		return admission.Errored(http.StatusBadRequest, err)
+	}
+	if !defaults.Spec.ApplyTo(providerSpec) {
+		return admission.Allowed("provider spec already set")
+	}
+	machine.Spec.ProviderSpec.Value, err = ovirtconfigv1.RawExtensionFromProviderSpec(providerSpec)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	defaulted, err := json.Marshal(machine)
+	if err != nil {
+		return admission.Errored(http.StatusInternalServerError, err)
+	}
+	return admission.PatchResponseFromRaw(req.Object.Raw, defaulted)
+}
+
+// defaultsName returns the name of the defaults selected by the machine, or by its
+// MachineSet if the machine doesn't select any
+func (d *MachineDefaulter) defaultsName(ctx context.Context, machine *machinev1.Machine) (string, error) {
+	if name := machine.Annotations[ovirtconfigv1.DefaultsAnnotation]; name != "" {
+		return name, nil
+	}
+	if machineSetName := machine.Labels[machineSetLabel]; machineSetName != "" {
+		machineSet := &machinev1.MachineSet{}
+		err := d.client.Get(ctx, types.NamespacedName{Namespace: machine.Namespace, Name: machineSetName}, machineSet)
+		if err != nil && !apierrors.IsNotFound(err) {
+			return "", err
+		}
+		if name := machineSet.Annotations[ovirtconfigv1.DefaultsAnnotation]; name != "" {
+			return name, nil
+		}
+	}
+	return ovirtconfigv1.DefaultsName, nil
+}